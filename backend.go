@@ -0,0 +1,148 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+// Backend is everything Frame needs from the outside world, the default talks to the browser's DOM
+type Backend interface {
+	// Snapshot returns the current value of every input with an id and, if it exists, the selection of the focused element
+	Snapshot(focusId string) (values map[string]string, selection [2]int, ok bool)
+	Patch(patches []Patch)
+	// Restore writes input values back and focuses focusId, a selection of {-1, -1} means the end of the value
+	Restore(values map[string]string, focusId string, selection [2]int)
+	ScrollIntoView(ids []string)
+	Download(files []File)
+	Notify(notes []Notification, ask bool)
+	// Measure is called with the ids passed to Measure once the frame's patches are applied, Rects returns
+	// the result at the start of the next frame
+	Measure(ids []string)
+	Rects() map[string]Rect
+	Location() string
+	SetLocation(location string)
+	RequestFrame()
+	Now() float64
+	// Flush is called at the end of every frame, for backends that send a frame's output all at once
+	Flush()
+}
+
+var (
+	backend Backend = browserBackend{}
+
+	// scroll and hover events can come in faster than frames, they only need one
+	frameRequested = false
+)
+
+type browserBackend struct{}
+
+func (browserBackend) Snapshot(focusId string) (map[string]string, [2]int, bool) {
+	values := map[string]string{}
+	inputs := js.Global.Get("document").Call("getElementsByTagName", "input")
+	for i := 0; i < inputs.Length(); i++ {
+		input := inputs.Index(i)
+		id := input.Get("id").String()
+		if id != "" {
+			values[id] = input.Get("value").String()
+		}
+	}
+
+	if focusId != "" {
+		elem := js.Global.Get("document").Call("getElementById", focusId)
+		if elem != nil {
+			return values, [2]int{elem.Get("selectionStart").Int(), elem.Get("selectionEnd").Int()}, true
+		}
+	}
+	return values, [2]int{}, false
+}
+
+func (browserBackend) Patch(patches []Patch) {
+	PatchDOM(patches, js.Global.Get("document").Get("body"))
+}
+
+func (browserBackend) Restore(values map[string]string, focusId string, selection [2]int) {
+	for id, value := range values {
+		elem := js.Global.Get("document").Call("getElementById", id)
+		if elem != nil {
+			elem.Set("value", value)
+		}
+	}
+
+	if focusId == "" {
+		return
+	}
+
+	elem := js.Global.Get("document").Call("getElementById", focusId)
+	if elem == nil {
+		return
+	}
+
+	// focus will normally cause the page to scroll, which we don't want, so scroll back afterward for browsers that
+	// ignore preventScroll, scrollable elements like virtual lists don't get scrolled back though
+	x := js.Global.Get("window").Get("scrollX").Int()
+	y := js.Global.Get("window").Get("scrollY").Int()
+	elem.Call("focus", js.M{"preventScroll": true})
+	js.Global.Get("window").Call("scrollTo", x, y)
+	if selection == [2]int{-1, -1} {
+		elem.Set("selectionStart", elem.Get("value").Get("length"))
+		elem.Set("selectionEnd", elem.Get("value").Get("length"))
+	} else {
+		elem.Set("selectionStart", selection[0])
+		elem.Set("selectionEnd", selection[1])
+	}
+}
+
+func (browserBackend) ScrollIntoView(ids []string) {
+	for _, id := range ids {
+		if elem := js.Global.Get("document").Call("getElementById", id); elem != nil {
+			elem.Call("scrollIntoView", js.M{"block": "nearest"})
+		}
+	}
+}
+
+func (browserBackend) Location() string {
+	l := js.Global.Get("window").Get("location")
+	if Routing == HistoryRouting {
+		return l.Get("pathname").String() + l.Get("search").String()
+	}
+	return l.Get("hash").String()
+}
+
+func (browserBackend) SetLocation(location string) {
+	js.Global.Get("history").Call("pushState", nil, "", location)
+}
+
+func (browserBackend) RequestFrame() {
+	if frameRequested {
+		return
+	}
+	frameRequested = true
+
+	js.Global.Get("window").Call("requestAnimationFrame", func() {
+		frameRequested = false
+		Frame()
+	})
+}
+
+// the DOM was patched as the frame went
+func (browserBackend) Flush() {}
+
+func (browserBackend) Now() float64 {
+	performance := js.Global.Get("performance")
+	if performance == js.Undefined || performance == nil {
+		return js.Global.Get("Date").Call("now").Float()
+	}
+	return performance.Call("now").Float()
+}
+
+// queryFlag reports whether name appears in the page's query string, as in ?worker&record
+func queryFlag(name string) bool {
+	search := js.Global.Get("window").Get("location").Get("search").String()
+	start := 1
+	for i := 1; i <= len(search); i++ {
+		if i == len(search) || search[i] == '&' {
+			if search[start:i] == name {
+				return true
+			}
+			start = i + 1
+		}
+	}
+	return false
+}
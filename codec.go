@@ -0,0 +1,202 @@
+package main
+
+// Wire format for patches and vnodes, version 1:
+//
+//	{"v":1,"p":[patch, ...]}
+//
+// each patch is an array of a type code, the location and a payload:
+//
+//	["r", [0,1], vnode]      replace
+//	["u", [0], {"k":"v"}]    update, an empty value removes the attribute
+//	["x", [0]]               remove last child
+//	["a", [0], vnode]        append child
+//
+// a vnode is a string for text, {"raw":"..."} for raw html and [tag, {attributes}, [children]] for elements.
+// Styles are already serialised into the style attribute by End, so they are not sent separately.
+
+const patchFormatVersion = 1
+
+var (
+	patchCodes = map[string]string{
+		patchReplace:         "r",
+		patchUpdate:          "u",
+		patchRemoveLastChild: "x",
+		patchAppendChild:     "a",
+	}
+	patchTypes = map[string]string{
+		"r": patchReplace,
+		"u": patchUpdate,
+		"x": patchRemoveLastChild,
+		"a": patchAppendChild,
+	}
+)
+
+func EncodePatches(patches []Patch) string {
+	w := &jsonWriter{}
+	writePatches(w, patches)
+	return w.Done()
+}
+
+func writePatches(w *jsonWriter, patches []Patch) {
+	w.Raw(`{"v":`)
+	w.Int(patchFormatVersion)
+	w.Raw(`,"p":[`)
+	for i, patch := range patches {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Raw("[")
+		w.String(patchCodes[patch.Type])
+		w.Raw(",")
+		w.Ints(patch.Location)
+		switch patch.Type {
+		case patchReplace, patchAppendChild:
+			w.Raw(",")
+			writeVNode(w, patch.VNode)
+		case patchUpdate:
+			w.Raw(",")
+			w.StringMap(patch.Attributes)
+		}
+		w.Raw("]")
+	}
+	w.Raw("]}")
+}
+
+func EncodeVNode(vnode *VNode) string {
+	checkLive(vnode)
+
+	w := &jsonWriter{}
+	writeVNode(w, vnode)
+	return w.Done()
+}
+
+func writeVNode(w *jsonWriter, vnode *VNode) {
+	switch vnode.Tag {
+	case tagText:
+		w.String(vnode.Data)
+	case tagRaw:
+		w.Raw(`{"raw":`)
+		w.String(vnode.Data)
+		w.Raw("}")
+	default:
+		w.Raw("[")
+		w.String(vnode.Tag)
+		w.Raw(",")
+		w.StringMap(vnode.Attributes)
+		w.Raw(",[")
+		for i, child := range vnode.Children {
+			if i > 0 {
+				w.Raw(",")
+			}
+			writeVNode(w, child)
+		}
+		w.Raw("]]")
+	}
+}
+
+func DecodePatches(s string) ([]Patch, error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	return readPatches(v)
+}
+
+func readPatches(v interface{}) ([]Patch, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, DecodeError("patches: expected object")
+	}
+	if version := jsonInt(m, "v"); version != patchFormatVersion {
+		return nil, DecodeError("patches: unsupported version " + itoa(version))
+	}
+
+	list, ok := m["p"].([]interface{})
+	if !ok {
+		return nil, DecodeError("patches: expected patch list")
+	}
+
+	patches := make([]Patch, 0, len(list))
+	for _, e := range list {
+		a, ok := e.([]interface{})
+		if !ok || len(a) < 2 {
+			return nil, DecodeError("patches: expected patch array")
+		}
+
+		code, _ := a[0].(string)
+		patch := Patch{Type: patchTypes[code], Location: jsonInts(a[1])}
+		switch patch.Type {
+		case patchReplace, patchAppendChild:
+			if len(a) != 3 {
+				return nil, DecodeError("patches: missing vnode")
+			}
+			vnode, err := readVNode(a[2], nil)
+			if err != nil {
+				return nil, err
+			}
+			patch.VNode = vnode
+		case patchUpdate:
+			if len(a) != 3 {
+				return nil, DecodeError("patches: missing attributes")
+			}
+			patch.Attributes = jsonStringMap(a[2])
+		case patchRemoveLastChild:
+		default:
+			return nil, DecodeError("patches: unknown patch type " + quote(code))
+		}
+		patches = append(patches, patch)
+	}
+	return patches, nil
+}
+
+func DecodeVNode(s string) (*VNode, error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	return readVNode(v, nil)
+}
+
+func readVNode(v interface{}, parent *VNode) (*VNode, error) {
+	var vnode *VNode
+	switch e := v.(type) {
+	case string:
+		vnode = NewVNode(tagText)
+		vnode.Data = e
+	case map[string]interface{}:
+		raw, ok := e["raw"].(string)
+		if !ok {
+			return nil, DecodeError("vnode: expected raw string")
+		}
+		vnode = NewVNode(tagRaw)
+		vnode.Data = raw
+	case []interface{}:
+		if len(e) != 3 {
+			return nil, DecodeError("vnode: expected [tag, attributes, children]")
+		}
+		tag, ok := e[0].(string)
+		if !ok || tag == "" {
+			return nil, DecodeError("vnode: expected tag")
+		}
+		children, ok := e[2].([]interface{})
+		if !ok {
+			return nil, DecodeError("vnode: expected children")
+		}
+
+		vnode = NewVNode(tag)
+		vnode.Attributes = jsonStringMap(e[1])
+		for _, c := range children {
+			child, err := readVNode(c, vnode)
+			if err != nil {
+				return nil, err
+			}
+			vnode.Children = append(vnode.Children, child)
+		}
+	default:
+		return nil, DecodeError("vnode: unexpected value")
+	}
+
+	hashVNode(vnode)
+	vnode.Parent = parent
+	return vnode, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// tree draws a body with f
+func tree(f func()) *VNode {
+	Init("body")
+	f()
+	return Done()
+}
+
+func item(id string, text string, done bool) {
+	Begin("li")
+	Id(id)
+	if done {
+		Attr("class", "completed")
+		Style("text-decoration", "line-through", "color", "#d9d9d9")
+	}
+	Tag("label", text)
+	End("li")
+}
+
+// each case diffs old against new, the patches are compared against testdata/patches/<name>.json
+var codecCases = []struct {
+	name string
+	old  func()
+	new  func()
+}{
+	{"first-frame", nil, func() {
+		item("a", "one", false)
+	}},
+	{"unchanged", func() {
+		item("a", "one", false)
+	}, func() {
+		item("a", "one", false)
+	}},
+	{"text", func() {
+		item("a", "one", false)
+	}, func() {
+		item("a", `"two" <b> & ünïcode`+"\n", false)
+	}},
+	{"attributes", func() {
+		item("a", "one", false)
+		Begin("input")
+		Attr("type", "text", "placeholder", "what")
+		End("input")
+	}, func() {
+		item("a", "one", true)
+		Begin("input")
+		Attr("type", "text", "value", "x")
+		End("input")
+	}},
+	{"append", func() {
+		item("a", "one", false)
+	}, func() {
+		item("a", "one", false)
+		item("b", "two", true)
+		Text("three")
+	}},
+	{"remove", func() {
+		item("a", "one", false)
+		item("b", "two", false)
+		item("c", "three", false)
+	}, func() {
+		item("a", "one", false)
+	}},
+	{"replace", func() {
+		item("a", "one", false)
+		Tag("p", "para")
+		Text("text")
+	}, func() {
+		item("b", "one", false)
+		Tag("div", "para")
+		UnsafeRaw("<em>raw</em>")
+	}},
+	{"raw", func() {
+		UnsafeRaw("<em>one</em>")
+	}, func() {
+		UnsafeRaw(`<em title="&quot;">two</em>`)
+	}},
+}
+
+func TestCodecGolden(t *testing.T) {
+	for _, c := range codecCases {
+		var o *VNode
+		if c.old != nil {
+			o = tree(c.old)
+		}
+		n := tree(c.new)
+		got := EncodePatches(DiffNodes(o, n))
+
+		path := filepath.Join("testdata", "patches", c.name+".json")
+		if *updateGolden {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(got+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v, run go test -update to write it", c.name, err)
+		}
+		if got+"\n" != string(want) {
+			t.Errorf("%s: patches changed\ngot  %s\nwant %s", c.name, got, want)
+		}
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, c := range codecCases {
+		n := tree(c.new)
+		encoded := EncodeVNode(n)
+		decoded, err := DecodeVNode(encoded)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if again := EncodeVNode(decoded); again != encoded {
+			t.Errorf("%s: vnode changed in a round trip\ngot  %s\nwant %s", c.name, again, encoded)
+		}
+		if decoded.hash != n.hash {
+			t.Errorf("%s: decoded vnode hashes differently", c.name)
+		}
+
+		// what a remote client does with the patches, which has to end up with the new tree
+		var o *VNode
+		if c.old != nil {
+			o, err = DecodeVNode(EncodeVNode(tree(c.old)))
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+		}
+		patches := EncodePatches(DiffNodes(o, n))
+		decodedPatches, err := DecodePatches(patches)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if again := EncodePatches(decodedPatches); again != patches {
+			t.Errorf("%s: patches changed in a round trip\ngot  %s\nwant %s", c.name, again, patches)
+		}
+		if got := EncodeVNode(PatchVNode(o, decodedPatches)); got != encoded {
+			t.Errorf("%s: patched tree differs\ngot  %s\nwant %s", c.name, got, encoded)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	patches := []string{
+		``,
+		`[]`,
+		`{"v":2,"p":[]}`,
+		`{"v":1}`,
+		`{"v":1,"p":[["r"]]}`,
+		`{"v":1,"p":[["r",[0]]]}`,
+		`{"v":1,"p":[["u",[0]]]}`,
+		`{"v":1,"p":[["z",[0]]]}`,
+		`{"v":1,"p":[["a",[0],["div",{}]]]}`,
+		`{"v":1,"p":[["a",[0],["",{},[]]]]}`,
+		`{"v":1,"p":[["a",[0],{"raw":1}]]}`,
+		`{"v":1,"p":[["a",[0],3]]}`,
+	}
+	for _, s := range patches {
+		if _, err := DecodePatches(s); err == nil {
+			t.Errorf("decoded %s", s)
+		}
+	}
+}
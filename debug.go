@@ -0,0 +1,220 @@
+package main
+
+const keyD = 68
+
+var (
+	// toggled with ctrl+shift+d
+	DebugOverlay = false
+
+	lastPatches []Patch
+)
+
+// DrawDebugOverlay should be called at the end of render so that it sees everything registered by the rest of the frame
+func DrawDebugOverlay() {
+	if !DebugOverlay {
+		return
+	}
+
+	// copy these first so that the overlay's own widgets don't show up
+	clickIds := sortedKeys(clickable)
+	doubleClickIds := sortedKeys(doubleClickable)
+	hoverableIds := sortedKeys(hoverable)
+	shortcutKeys := []string{}
+	for _, key := range sortedKeys(shortcuts) {
+		shortcutKeys = append(shortcutKeys, quote(key))
+	}
+	scrollableIds := []string{}
+	for _, id := range sortedKeys(scrollable) {
+		scrollableIds = append(scrollableIds, id+" "+px(scrollTops[id]))
+	}
+	keyupIds := []string{}
+	for _, id := range sortedKeys(keyupable) {
+		codes := ""
+		for i, code := range keyupableCodes[id] {
+			if i > 0 {
+				codes += ","
+			}
+			codes += itoa(code)
+		}
+		keyupIds = append(keyupIds, id+" ["+codes+"]")
+	}
+
+	hovered := []string{}
+	for i, id := range hoverIds {
+		vnode := findVNode(Root, id)
+		if vnode == nil {
+			hovered = append(hovered, id+" (not rendered)")
+			continue
+		}
+
+		// outline the innermost hovered widget more strongly than its ancestors
+		outline := "outline:1px dashed rgba(255, 0, 255, 0.5);"
+		if i == 0 {
+			outline = "outline:2px solid #f0f;"
+		}
+		vnode.Attributes["style"] += outline
+		rehashVNode(vnode)
+
+		if debug, ok := vnode.Attributes["data-debug"]; ok {
+			id += " (" + debug + ")"
+		}
+		hovered = append(hovered, id)
+	}
+
+	focus := []string{}
+	if focusId != "" {
+		focus = append(focus, focusId+" selection "+itoa(focusSelection[0])+"-"+itoa(focusSelection[1]))
+	}
+
+	values := []string{}
+	for _, id := range sortedKeys(InputValues) {
+		values = append(values, id+" = "+quote(InputValues[id]))
+	}
+
+	patches := []string{}
+	for _, patch := range lastPatches {
+		line := patch.Type + " @" + formatLocation(patch.Location)
+		if patch.VNode != nil {
+			line += " <" + patch.VNode.Tag + ">"
+		}
+		for _, k := range sortedKeys(patch.Attributes) {
+			line += " " + k
+		}
+		patches = append(patches, line)
+	}
+
+	timings := []string{}
+	if p, ok := LastProfile(); ok {
+		timings = append(timings,
+			"frame "+itoa(p.Frame)+" total "+formatMs(p.TotalMs),
+			"input "+formatMs(p.InputMs)+" render "+formatMs(p.RenderMs)+" diff "+formatMs(p.DiffMs),
+			"patch "+formatMs(p.PatchMs)+" restore "+formatMs(p.RestoreMs),
+			"vnodes "+itoa(p.VNodesCreated)+" dom nodes "+itoa(p.DOMNodesCreated),
+		)
+	}
+
+	Div(func() {
+		Id("debug-overlay")
+
+		Style(
+			"position", "fixed",
+			"top", "0px",
+			"right", "0px",
+			"width", "360px",
+			"max-height", "100%",
+			"overflow", "hidden",
+			"padding", "8px",
+			"background", "rgba(0, 0, 0, 0.8)",
+			"color", "#ddd",
+			"font", "11px/1.3em monospace",
+			"white-space", "pre-wrap",
+			"word-break", "break-all",
+			"z-index", "1000",
+			"pointer-events", "none",
+		)
+
+		drawDebugSection("clickable", clickIds)
+		drawDebugSection("double clickable", doubleClickIds)
+		drawDebugSection("hoverable", hoverableIds)
+		drawDebugSection("keyupable", keyupIds)
+		drawDebugSection("scrollable", scrollableIds)
+		drawDebugSection("shortcuts", shortcutKeys)
+		drawDebugSection("hovering", hovered)
+		drawDebugSection("focus", focus)
+		drawDebugSection("input values", values)
+		drawDebugSection("last patches", patches)
+		drawDebugSection("profile", timings)
+	})
+}
+
+func drawDebugSection(title string, lines []string) {
+	Div(func() {
+		Style("margin-bottom", "6px")
+
+		Div(func() {
+			Style("color", "#f0f")
+			Text(title + " (" + itoa(len(lines)) + ")")
+		})
+
+		for _, line := range lines {
+			Div(line)
+		}
+	})
+}
+
+func findVNode(vnode *VNode, id string) *VNode {
+	if vnode == nil {
+		return nil
+	}
+	if vnode.Attributes["id"] == id {
+		return vnode
+	}
+	for _, child := range vnode.Children {
+		if found := findVNode(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch v := m.(type) {
+	case map[string]bool:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]Rect:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]float64:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sortStrings(keys)
+	return keys
+}
+
+func formatLocation(loc []int) string {
+	s := ""
+	for i, index := range loc {
+		if i > 0 {
+			s += "."
+		}
+		s += itoa(index)
+	}
+	if s == "" {
+		return "root"
+	}
+	return s
+}
+
+func formatMs(ms float64) string {
+	hundredths := int(ms*100 + 0.5)
+	fraction := itoa(hundredths % 100)
+	if len(fraction) == 1 {
+		fraction = "0" + fraction
+	}
+	return itoa(hundredths/100) + "." + fraction + "ms"
+}
+
+func quote(s string) string {
+	quoted := "\""
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			quoted += "\\" + string(c)
+		case '\n':
+			quoted += "\\n"
+		default:
+			quoted += string(c)
+		}
+	}
+	return quoted + "\""
+}
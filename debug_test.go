@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDebugOverlay(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	a.frame("", nil)
+	if findVNode(PreviousRoot, "debug-overlay") != nil {
+		t.Fatal("the overlay is open before it was asked for")
+	}
+
+	a.input(InputEvent{Type: eventDebug})
+	a.input(InputEvent{Type: eventHover, Ids: []string{"todo-item-0"}})
+	a.input(InputEvent{Type: eventFocus, Id: "new-todo"})
+	a.frame("", map[string]string{"new-todo": "typing"})
+
+	overlay := findVNode(PreviousRoot, "debug-overlay")
+	if overlay == nil {
+		t.Fatal("the overlay didn't open")
+	}
+	shown := EncodeVNode(overlay)
+	for _, want := range []string{"checkbox-todo-item-0", "todo-item-0", "new-todo = \\\"typing\\\"", "replace @"} {
+		if !strings.Contains(shown, want) {
+			t.Errorf("the overlay doesn't show %s: %s", want, shown)
+		}
+	}
+	if style := findVNode(PreviousRoot, "todo-item-0").Attributes["style"]; !strings.Contains(style, "outline:2px solid #f0f;") {
+		t.Errorf("the hovered todo isn't outlined: %s", style)
+	}
+
+	a.input(InputEvent{Type: eventDebug})
+	a.frame("", nil)
+	if findVNode(PreviousRoot, "debug-overlay") != nil || strings.Contains(findVNode(PreviousRoot, "todo-item-0").Attributes["style"], "outline") {
+		t.Fatal("the overlay didn't close")
+	}
+}
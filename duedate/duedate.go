@@ -0,0 +1,419 @@
+// Package duedate is due dates for todos: reading them from the end of a todo's text, as in "pay rent tomorrow",
+// and deciding when they are overdue. It only uses the standard library.
+package duedate
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Due is a day, or a moment when HasTime is set. The zero Due means no due date.
+type Due struct {
+	// midnight at the start of the day unless HasTime
+	At      time.Time
+	HasTime bool
+}
+
+// Day is the due date for the whole day t falls on
+func Day(t time.Time) Due {
+	return Due{At: startOfDay(t)}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return clockOn(t, 0, 0)
+}
+
+// clockOn is hour:minute on the day t falls on. Adding hours to midnight would be off by one on days when the
+// clocks change.
+func clockOn(t time.Time, hour int, minute int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, hour, minute, 0, 0, t.Location())
+}
+
+func (d Due) IsZero() bool {
+	return d.At.IsZero()
+}
+
+func (d Due) Equal(other Due) bool {
+	return d.HasTime == other.HasTime && d.At.Equal(other.At)
+}
+
+// Overdue reports whether now is past the due time, or past the end of the due day
+func (d Due) Overdue(now time.Time) bool {
+	if d.IsZero() {
+		return false
+	}
+	if d.HasTime {
+		return now.After(d.At)
+	}
+	return !now.Before(d.At.AddDate(0, 0, 1))
+}
+
+// DueBy reports whether d is on or before the day now falls on
+func (d Due) DueBy(now time.Time) bool {
+	return !d.IsZero() && d.At.Before(startOfDay(now).AddDate(0, 0, 1))
+}
+
+// RemindAt is when to remind about d, the due time or 9am on the due day
+func (d Due) RemindAt() time.Time {
+	if d.HasTime {
+		return d.At
+	}
+	return clockOn(d.At, 9, 0)
+}
+
+// String is 2006-01-02, or 2006-01-02T15:04 with a time, in d's location. It's what Parse reads back.
+func (d Due) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	if d.HasTime {
+		return d.At.Format("2006-01-02T15:04")
+	}
+	return d.At.Format("2006-01-02")
+}
+
+// ParseString reads what String writes, in loc
+func ParseString(s string, loc *time.Location) (Due, error) {
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, loc); err == nil {
+		return Due{At: t, HasTime: true}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	if err != nil {
+		return Due{}, err
+	}
+	return Due{At: t}, nil
+}
+
+// Label describes d for people relative to now, as in "today", "Fri 5pm" or "Oct 21"
+func (d Due) Label(now time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+
+	today := startOfDay(now)
+	day := startOfDay(d.At)
+	label := ""
+	switch days := int(day.Sub(today).Hours()/24 + 0.5); {
+	case day.Equal(today):
+		label = "today"
+	case day.Equal(today.AddDate(0, 0, 1)):
+		label = "tomorrow"
+	case day.Equal(today.AddDate(0, 0, -1)):
+		label = "yesterday"
+	case days > 0 && days < 7:
+		label = d.At.Format("Mon")
+	case d.At.Year() == now.Year():
+		label = d.At.Format("Jan 2")
+	default:
+		label = d.At.Format("Jan 2 2006")
+	}
+
+	if d.HasTime {
+		if d.At.Minute() == 0 {
+			label += " " + d.At.Format("3pm")
+		} else {
+			label += " " + d.At.Format("3:04pm")
+		}
+	}
+	return label
+}
+
+// the longest phrase Parse looks for, as in "due on next friday at 5pm"
+const maxPhraseWords = 6
+
+// Parse looks for a due date at the end of text and returns the text without it. It understands phrases like
+// "today", "tomorrow at 5pm", "friday", "next week", "in 3 days", "oct 21", "21st october", "2026-10-21" and
+// "at 17:30", optionally after "due", "by" or "on". Weekdays mean the next one after today, "this friday" can
+// be today. Dates without a year are the next time they come around. Text that is only a date is left alone.
+func Parse(text string, now time.Time) (string, Due) {
+	words := strings.Fields(text)
+	start := len(words) - maxPhraseWords
+	if start < 1 {
+		start = 1
+	}
+
+	// the longest phrase wins, so that "next friday" isn't read as "friday"
+	for k := start; k < len(words); k++ {
+		if due, ok := parsePhrase(words[k:], now); ok {
+			return strings.Join(words[:k], " "), due
+		}
+	}
+	return text, Due{}
+}
+
+func parsePhrase(words []string, now time.Time) (Due, bool) {
+	lower := []string{}
+	for _, w := range words {
+		lower = append(lower, strings.ToLower(strings.TrimRight(w, ",.!")))
+	}
+	words = lower
+
+	for len(words) > 0 && (words[0] == "due" || words[0] == "by" || words[0] == "on") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return Due{}, false
+	}
+
+	// a day, then maybe a time
+	if day, n, ok := parseDay(words, now); ok {
+		words = words[n:]
+		if len(words) == 0 {
+			return Due{At: day}, true
+		}
+		if words[0] == "at" {
+			words = words[1:]
+		}
+		if hour, minute, n, _, ok := parseClock(words); ok && n == len(words) {
+			return Due{At: clockOn(day, hour, minute), HasTime: true}, true
+		}
+		return Due{}, false
+	}
+
+	// a time, then maybe a day
+	at := words[0] == "at"
+	if at {
+		words = words[1:]
+	}
+	hour, minute, n, meridiem, ok := parseClock(words)
+	if !ok {
+		return Due{}, false
+	}
+	words = words[n:]
+
+	if len(words) == 0 {
+		// a bare 3:16 is as likely to be a bible verse or a score as a time
+		if !at && !meridiem {
+			return Due{}, false
+		}
+		// the next time the clock shows it
+		at := clockOn(now, hour, minute)
+		if !at.After(now) {
+			at = clockOn(startOfDay(now).AddDate(0, 0, 1), hour, minute)
+		}
+		return Due{At: at, HasTime: true}, true
+	}
+
+	if words[0] == "on" {
+		words = words[1:]
+	}
+	if day, n, ok := parseDay(words, now); ok && n == len(words) {
+		return Due{At: clockOn(day, hour, minute), HasTime: true}, true
+	}
+	return Due{}, false
+}
+
+// abbreviations that are also words, like "sun" and "sat", are left out since they end plenty of todos
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"tue":       time.Tuesday,
+	"tues":      time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"thu":       time.Thursday,
+	"thurs":     time.Thursday,
+	"friday":    time.Friday,
+	"fri":       time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// parseDay reads a day from the start of words and returns its midnight and how many words it took
+func parseDay(words []string, now time.Time) (time.Time, int, bool) {
+	today := startOfDay(now)
+	w := words[0]
+
+	switch w {
+	case "today", "tonight":
+		return today, 1, true
+	case "tomorrow", "tmrw", "tmr":
+		return today.AddDate(0, 0, 1), 1, true
+	}
+
+	if weekday, ok := weekdays[w]; ok {
+		return nextWeekday(today, weekday, 1), 1, true
+	}
+
+	if len(words) >= 2 {
+		switch w {
+		case "this":
+			if weekday, ok := weekdays[words[1]]; ok {
+				return nextWeekday(today, weekday, 0), 2, true
+			}
+		case "next":
+			if weekday, ok := weekdays[words[1]]; ok {
+				return nextWeekday(today, weekday, 1), 2, true
+			}
+			switch words[1] {
+			case "week":
+				return today.AddDate(0, 0, 7), 2, true
+			case "month":
+				return today.AddDate(0, 1, 0), 2, true
+			}
+		}
+	}
+
+	// in 3 days, in a week
+	if w == "in" && len(words) >= 3 {
+		n, err := strconv.Atoi(words[1])
+		if words[1] == "a" || words[1] == "an" || words[1] == "one" {
+			n, err = 1, nil
+		}
+		if err == nil && n > 0 {
+			switch strings.TrimSuffix(words[2], "s") {
+			case "day":
+				return today.AddDate(0, 0, n), 3, true
+			case "week":
+				return today.AddDate(0, 0, 7*n), 3, true
+			case "month":
+				return today.AddDate(0, n, 0), 3, true
+			}
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", w, now.Location()); err == nil {
+		return t, 1, true
+	}
+
+	// oct 21, oct 21 2027, 21st october
+	if len(words) >= 2 {
+		month, day, ok := months[w], 0, false
+		if month != 0 {
+			day, ok = parseDayOfMonth(words[1])
+		} else if day, ok = parseDayOfMonth(w); ok {
+			month = months[words[1]]
+		}
+		if month != 0 && ok {
+			if len(words) >= 3 && len(words[2]) == 4 {
+				if year, err := strconv.Atoi(words[2]); err == nil {
+					t, ok := date(year, month, day, now.Location())
+					return t, 3, ok
+				}
+			}
+			t, ok := date(now.Year(), month, day, now.Location())
+			if !ok || t.Before(today) {
+				t, ok = date(now.Year()+1, month, day, now.Location())
+			}
+			return t, 2, ok
+		}
+	}
+
+	return time.Time{}, 0, false
+}
+
+// nextWeekday is the first weekday at least after days from today
+func nextWeekday(today time.Time, weekday time.Weekday, after int) time.Time {
+	t := today.AddDate(0, 0, after)
+	for t.Weekday() != weekday {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// date is time.Date, except that it fails for days the month doesn't have instead of moving on to the next month
+func date(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return t, t.Day() == day
+}
+
+// parseDayOfMonth reads 21, 21st or 1st
+func parseDayOfMonth(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+	day, err := strconv.Atoi(w)
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+// parseClock reads 5pm, 5:30pm, 5 pm, 17:30 or noon from the start of words and returns the hour, the minute, how
+// many words it took and whether it had am, pm or noon to show it is a time. A number on its own isn't a time.
+func parseClock(words []string) (int, int, int, bool, bool) {
+	if len(words) == 0 {
+		return 0, 0, 0, false, false
+	}
+	w, n := words[0], 1
+	if w == "noon" {
+		return 12, 0, 1, true, true
+	}
+	if len(words) >= 2 && (words[1] == "am" || words[1] == "pm") {
+		w, n = w+words[1], 2
+	}
+
+	suffix := ""
+	if strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm") {
+		suffix = w[len(w)-2:]
+		w = w[:len(w)-2]
+	}
+
+	hour, minute := 0, 0
+	ok := false
+	if i := strings.Index(w, ":"); i != -1 {
+		if hour, ok = parseDigits(w[:i], 2); !ok {
+			return 0, 0, 0, false, false
+		}
+		if len(w[i+1:]) != 2 {
+			return 0, 0, 0, false, false
+		}
+		if minute, ok = parseDigits(w[i+1:], 2); !ok || minute > 59 {
+			return 0, 0, 0, false, false
+		}
+	} else {
+		if suffix == "" {
+			return 0, 0, 0, false, false
+		}
+		if hour, ok = parseDigits(w, 2); !ok {
+			return 0, 0, 0, false, false
+		}
+	}
+
+	switch suffix {
+	case "":
+		if hour > 23 {
+			return 0, 0, 0, false, false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, n, suffix != "", true
+}
+
+// parseDigits reads a number of at most max digits, unlike strconv.Atoi it doesn't take a sign
+func parseDigits(s string, max int) (int, bool) {
+	if len(s) == 0 || len(s) > max {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
@@ -0,0 +1,168 @@
+package duedate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// a monday
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		text string
+		due  string
+	}{
+		{"pay rent tomorrow", "pay rent", "2026-10-20"},
+		{"pay rent", "pay rent", ""},
+		{"tomorrow", "tomorrow", ""},
+		{"meeting friday at 3pm", "meeting", "2026-10-23T15:00"},
+		{"meeting due next friday", "meeting", "2026-10-23"},
+		{"standup monday", "standup", "2026-10-26"},
+		{"standup this monday", "standup", "2026-10-19"},
+		{"call bob at 5pm", "call bob", "2026-10-19T17:00"},
+		{"call bob at 9am", "call bob", "2026-10-20T09:00"},
+		{"call bob 17:30 on tuesday", "call bob", "2026-10-20T17:30"},
+		{"report in 3 days", "report", "2026-10-22"},
+		{"report in a week", "report", "2026-10-26"},
+		{"taxes by apr 15", "taxes", "2027-04-15"},
+		{"party on 21st october", "party", "2026-10-21"},
+		{"party oct 21, 2027", "party", "2027-10-21"},
+		{"ship 2026-11-01 at 12:00pm", "ship", "2026-11-01T12:00"},
+		{"fix the sun", "fix the sun", ""},
+		{"read chapter 5", "read chapter 5", ""},
+		{"lunch at noon today", "lunch", "2026-10-19T12:00"},
+		{"buy  milk  today", "buy milk", "2026-10-19"},
+		{"stand by", "stand by", ""},
+		{"wake 12am tomorrow", "wake", "2026-10-20T00:00"},
+		{"Read John 3:16", "Read John 3:16", ""},
+		{"score was 2:10", "score was 2:10", ""},
+		{"train at 17:30", "train", "2026-10-19T17:30"},
+		{"train 5:30pm", "train", "2026-10-19T17:30"},
+		{"train tomorrow 17:30", "train", "2026-10-20T17:30"},
+		{"rent feb 30", "rent feb 30", ""},
+		{"rent 31st april", "rent 31st april", ""},
+		{"rent feb 28", "rent", "2027-02-28"},
+		{"rent feb 29 2028", "rent", "2028-02-29"},
+		{"rent feb 29 2027", "rent feb 29 2027", ""},
+		{"rent 2027-02-29", "rent 2027-02-29", ""},
+		{"train at -1:30", "train at -1:30", ""},
+		{"train at +5pm", "train at +5pm", ""},
+		{"train at +5:30", "train at +5:30", ""},
+		{"train at 5:+3", "train at 5:+3", ""},
+		{"train at 24:00", "train at 24:00", ""},
+		{"train at 12:60", "train at 12:60", ""},
+		{"train at 13pm", "train at 13pm", ""},
+		{"train at 0am", "train at 0am", ""},
+		{"train at 007pm", "train at 007pm", ""},
+	}
+	for _, test := range tests {
+		text, due := Parse(test.in, now)
+		if text != test.text || due.String() != test.due {
+			t.Errorf("Parse(%q) = %q, %q, want %q, %q", test.in, text, due.String(), test.text, test.due)
+		}
+	}
+}
+
+func TestParseAcrossClockChanges(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	tests := []struct {
+		now  time.Time
+		in   string
+		hour int
+	}{
+		// the clocks go forward early on the 8th of march
+		{time.Date(2026, 3, 7, 12, 0, 0, 0, loc), "tomorrow at 5pm", 17},
+		{time.Date(2026, 3, 8, 12, 0, 0, 0, loc), "at 9pm", 21},
+		// and back on the 1st of november
+		{time.Date(2026, 11, 1, 12, 0, 0, 0, loc), "today at 9pm", 21},
+		{time.Date(2026, 10, 31, 12, 0, 0, 0, loc), "5pm tomorrow", 17},
+	}
+	for _, test := range tests {
+		_, due := Parse("x "+test.in, test.now)
+		if due.At.Hour() != test.hour || due.At.Minute() != 0 {
+			t.Errorf("%q at %v is due %v", test.in, test.now, due.At)
+		}
+	}
+
+	if at := Day(time.Date(2026, 11, 1, 12, 0, 0, 0, loc)).RemindAt(); at.Hour() != 9 {
+		t.Errorf("reminder at %v", at)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	// a monday afternoon
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+	at := func(days int, hour int, minute int) Due {
+		return Due{At: time.Date(2026, 10, 19+days, hour, minute, 0, 0, time.UTC), HasTime: true}
+	}
+	tests := []struct {
+		due   Due
+		label string
+	}{
+		{Due{}, ""},
+		{Day(now), "today"},
+		{Day(now.AddDate(0, 0, 1)), "tomorrow"},
+		{Day(now.AddDate(0, 0, -1)), "yesterday"},
+		{Day(now.AddDate(0, 0, 4)), "Fri"},
+		{Day(now.AddDate(0, 0, 6)), "Sun"},
+		{Day(now.AddDate(0, 0, 7)), "Oct 26"},
+		{Day(now.AddDate(0, 0, -3)), "Oct 16"},
+		{Day(now.AddDate(0, 0, 30)), "Nov 18"},
+		{Day(now.AddDate(1, 0, 0)), "Oct 19 2027"},
+		{at(0, 14, 29), "today 2:29pm"},
+		{at(0, 17, 0), "today 5pm"},
+		{at(1, 9, 0), "tomorrow 9am"},
+		{at(2, 0, 0), "Wed 12am"},
+		{at(0, 12, 0), "today 12pm"},
+	}
+	for _, test := range tests {
+		if label := test.due.Label(now); label != test.label {
+			t.Errorf("%v.Label() = %q, want %q", test.due, label, test.label)
+		}
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	day := Day(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	at := Due{At: time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC), HasTime: true}
+	tests := []struct {
+		due     Due
+		now     time.Time
+		overdue bool
+		dueBy   bool
+	}{
+		{Due{}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), false, false},
+		{day, time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC), false, false},
+		{day, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), false, true},
+		{day, time.Date(2026, 10, 19, 23, 59, 0, 0, time.UTC), false, true},
+		{day, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), true, true},
+		{at, time.Date(2026, 10, 19, 16, 59, 0, 0, time.UTC), false, true},
+		{at, time.Date(2026, 10, 19, 17, 0, 0, 0, time.UTC), false, true},
+		{at, time.Date(2026, 10, 19, 17, 1, 0, 0, time.UTC), true, true},
+		{at, time.Date(2026, 10, 18, 17, 1, 0, 0, time.UTC), false, false},
+	}
+	for _, test := range tests {
+		if overdue := test.due.Overdue(test.now); overdue != test.overdue {
+			t.Errorf("%v.Overdue(%v) = %v, want %v", test.due, test.now, overdue, test.overdue)
+		}
+		if dueBy := test.due.DueBy(test.now); dueBy != test.dueBy {
+			t.Errorf("%v.DueBy(%v) = %v, want %v", test.due, test.now, dueBy, test.dueBy)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, due := range []Due{Day(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)), {At: time.Date(2026, 10, 19, 17, 30, 0, 0, time.UTC), HasTime: true}} {
+		back, err := ParseString(due.String(), time.UTC)
+		if err != nil || !back.Equal(due) {
+			t.Errorf("ParseString(%q) = %v, %v", due.String(), back, err)
+		}
+	}
+	if _, err := ParseString("tomorrow", time.UTC); err == nil {
+		t.Error("ParseString read tomorrow")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+// File is a text file chosen in a FileInput or offered with Download
+type File struct {
+	Name string
+	// media type, such as "text/csv"
+	Type string
+	Data string
+}
+
+var (
+	uploadable map[string]bool
+
+	uploadId  = ""
+	upload    = File{}
+	downloads = []File{}
+)
+
+// FileInput draws an <input type="file"> and returns the file chosen in it, read as text, on the frame after it has been
+// read. accept is passed on as is, as in ".json,.csv" or "" for anything.
+func FileInput(id string, accept string) (File, bool) {
+	Begin("input")
+	Id(id)
+	Attr("type", "file")
+	if accept != "" {
+		Attr("accept", accept)
+	}
+	End("input")
+
+	uploadable[id] = true
+	if uploadId == id {
+		return upload, true
+	}
+	return File{}, false
+}
+
+// Download offers f to the user, as if following a link to it, once this frame is applied
+func Download(f File) {
+	downloads = append(downloads, f)
+}
+
+// readUpload reads the file chosen in input and sends it as a file event, which can take a while for big files
+func readUpload(input *js.Object) {
+	files := input.Get("files")
+	if files == nil || files == js.Undefined || files.Length() == 0 {
+		return
+	}
+
+	id := input.Get("id").String()
+	file := files.Index(0)
+	reader := js.Global.Get("FileReader").New()
+	reader.Set("onload", func() {
+		Input(InputEvent{Type: eventFile, Id: id, File: File{
+			Name: file.Get("name").String(),
+			Type: file.Get("type").String(),
+			Data: reader.Get("result").String(),
+		}})
+	})
+	reader.Call("readAsText", file)
+
+	// so that choosing the same file again is still a change
+	input.Set("value", "")
+}
+
+func (browserBackend) Download(files []File) {
+	for _, f := range files {
+		blob := js.Global.Get("Blob").New([]interface{}{f.Data}, js.M{"type": f.Type})
+		url := js.Global.Get("URL").Call("createObjectURL", blob)
+
+		a := js.Global.Get("document").Call("createElement", "a")
+		a.Set("href", url)
+		a.Set("download", f.Name)
+		a.Get("style").Set("display", "none")
+		js.Global.Get("document").Get("body").Call("appendChild", a)
+		a.Call("click")
+		a.Get("parentNode").Call("removeChild", a)
+
+		// once the download has had a chance to start
+		js.Global.Call("setTimeout", func() {
+			js.Global.Get("URL").Call("revokeObjectURL", url)
+		}, 0)
+	}
+}
+
+func writeFiles(w *jsonWriter, files []File) {
+	w.Raw("[")
+	for i, f := range files {
+		if i > 0 {
+			w.Raw(",")
+		}
+		writeFile(w, f)
+	}
+	w.Raw("]")
+}
+
+func writeFile(w *jsonWriter, f File) {
+	w.Raw(`{"name":`)
+	w.String(f.Name)
+	w.Raw(`,"type":`)
+	w.String(f.Type)
+	w.Raw(`,"data":`)
+	w.String(f.Data)
+	w.Raw("}")
+}
+
+func readFiles(v interface{}) []File {
+	a, _ := v.([]interface{})
+	files := []File{}
+	for _, e := range a {
+		files = append(files, readFile(e))
+	}
+	return files
+}
+
+func readFile(v interface{}) File {
+	m, _ := v.(map[string]interface{})
+	return File{Name: jsonString(m, "name"), Type: jsonString(m, "type"), Data: jsonString(m, "data")}
+}
@@ -0,0 +1,154 @@
+package main
+
+var (
+	// id of the element focus is confined to this frame, see TrapFocus
+	focusTrap = ""
+	// the trap that was in effect at the last commit and where focus was before it started
+	activeTrap   = ""
+	trapReturnId = ""
+
+	// FocusFallback picks what gets focus when the focused element is removed, given the focus order of the frame
+	// it was removed from and of the new frame. An empty result leaves nothing focused.
+	FocusFallback = FocusNearest
+)
+
+// FocusNearest is the default FocusFallback, it picks the next element that is still there or, failing that, the previous one
+func FocusNearest(removed string, before []string, after []string) string {
+	index := -1
+	for i, id := range before {
+		if id == removed {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return ""
+	}
+
+	for _, id := range before[index+1:] {
+		if contains(after, id) {
+			return id
+		}
+	}
+	for i := index - 1; i >= 0; i-- {
+		if contains(after, before[i]) {
+			return before[i]
+		}
+	}
+	return ""
+}
+
+// TrapFocus keeps focus inside the element id for as long as it is called each frame, as for a modal dialog.
+// Tab and shift+tab cycle through its elements, and focus goes back to where it was once the trap is gone.
+func TrapFocus(id string) {
+	focusTrap = id
+}
+
+// FocusNext moves focus to the next focusable element of the previous frame, wrapping around, and stays inside any trap
+func FocusNext() {
+	moveFocus(1)
+}
+
+func FocusPrev() {
+	moveFocus(-1)
+}
+
+func moveFocus(direction int) {
+	scope := PreviousRoot
+	if activeTrap != "" {
+		if trap := findVNode(PreviousRoot, activeTrap); trap != nil {
+			scope = trap
+		}
+	}
+
+	order := focusOrder(scope)
+	if len(order) == 0 {
+		return
+	}
+
+	next := 0
+	if direction < 0 {
+		next = len(order) - 1
+	}
+	for i, id := range order {
+		if id == focusId {
+			next = (i + direction + len(order)) % len(order)
+			break
+		}
+	}
+
+	Focus(order[next])
+	Rerender()
+}
+
+// focusOrder returns the ids of the focusable elements under vnode in document order, which is tab order as long as
+// nothing uses a positive tabindex
+func focusOrder(vnode *VNode) []string {
+	order := []string{}
+	var walk func(v *VNode)
+	walk = func(v *VNode) {
+		if v.Tag == tagText || v.Tag == tagRaw {
+			return
+		}
+		if focusable(v) {
+			order = append(order, v.Attributes["id"])
+		}
+		for _, child := range v.Children {
+			walk(child)
+		}
+	}
+	if vnode != nil {
+		walk(vnode)
+	}
+	return order
+}
+
+func focusable(v *VNode) bool {
+	if v.Attributes["id"] == "" {
+		return false
+	}
+	if _, ok := v.Attributes["disabled"]; ok {
+		return false
+	}
+	if tabindex, ok := v.Attributes["tabindex"]; ok {
+		return tabindex != "-1"
+	}
+
+	switch v.Tag {
+	case "input", "textarea", "select", "button":
+		return true
+	case "a":
+		return v.Attributes["href"] != ""
+	}
+	return false
+}
+
+// updateFocus runs when root replaces old on screen and decides where focus should be before it is restored
+func updateFocus(old *VNode, root *VNode) {
+	if focusTrap != activeTrap {
+		switch {
+		case activeTrap == "":
+			trapReturnId = focusId
+		case focusTrap == "":
+			if trapReturnId != "" && findVNode(root, trapReturnId) != nil {
+				Focus(trapReturnId)
+			}
+			trapReturnId = ""
+		}
+		activeTrap = focusTrap
+	}
+
+	// focus can be asked for an element that only shows up in the next frame, like an edit field, that isn't a removal
+	if focusId != "" && old != nil && findVNode(old, focusId) != nil && findVNode(root, focusId) == nil {
+		Focus(FocusFallback(focusId, focusOrder(old), focusOrder(root)))
+	}
+
+	if focusTrap != "" {
+		trap := findVNode(root, focusTrap)
+		if trap != nil && (focusId == "" || findVNode(trap, focusId) == nil) {
+			if order := focusOrder(trap); len(order) > 0 {
+				Focus(order[0])
+			}
+		}
+	}
+}
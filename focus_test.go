@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestFocusTrap(t *testing.T) {
+	newTestApp(t, nil)
+	frame := func(trapped bool) {
+		focusTrap = ""
+		root := tree(func() {
+			Button("outside", "", "Open")
+			if trapped {
+				Div(func() {
+					Id("dialog")
+					TrapFocus("dialog")
+					Tag("input", func() {
+						Id("first")
+					})
+					Button("middle", "", "Middle")
+					Tag("button", func() {
+						Id("disabled")
+						Attr("disabled", "")
+					})
+					Begin("a")
+					Id("last")
+					Attr("href", "#/")
+					End("a")
+				})
+			}
+		})
+		Commit(root)
+	}
+
+	Focus("outside")
+	frame(false)
+
+	// focus goes into the trap when it starts
+	frame(true)
+	if focusId != "first" {
+		t.Fatalf("focus is on %q when the trap starts", focusId)
+	}
+
+	// tab and shift+tab wrap around inside the trap, leaving out what can't be focused
+	for _, c := range []struct {
+		from      string
+		direction int
+		to        string
+	}{
+		{"first", 1, "middle"},
+		{"middle", 1, "last"},
+		{"last", 1, "first"},
+		{"first", -1, "last"},
+		{"last", -1, "middle"},
+	} {
+		Focus(c.from)
+		applyInput(InputEvent{Type: eventFocusMove, Code: c.direction})
+		if focusId != c.to {
+			t.Errorf("moving %d from %q went to %q, want %q", c.direction, c.from, focusId, c.to)
+		}
+	}
+
+	// and it goes back to where it was when the trap ends
+	frame(false)
+	if focusId != "outside" {
+		t.Fatalf("focus is on %q after the trap", focusId)
+	}
+}
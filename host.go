@@ -0,0 +1,197 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+// host owns the DOM on behalf of a messageBackend that runs render() somewhere else
+type host struct {
+	send func(msg string)
+
+	wanted    bool // the other side asked for a frame
+	waiting   bool // a frame was sent and its output has not been applied yet
+	scheduled bool // an animation frame is pending
+}
+
+// StartWorker runs render() in a web worker loaded from script, which should call RunWorker, and applies its patches here
+func StartWorker(script string) {
+	worker := js.Global.Get("Worker").New(script)
+	// the worker has no storage of its own, it keeps its todos here
+	worker.Call("postMessage", storageMessage())
+	h := startHost(func(msg string) {
+		worker.Call("postMessage", msg)
+	})
+
+	worker.Call("addEventListener", "message", func(e *js.Object) {
+		h.Receive(e.Get("data").String())
+	})
+}
+
+// ConnectRemote renders with a server started from server.go, url is its websocket endpoint
+func ConnectRemote(url string) {
+	ws := js.Global.Get("WebSocket").New(url)
+
+	var h *host
+	ws.Set("onopen", func() {
+		h = startHost(func(msg string) {
+			ws.Call("send", msg)
+		})
+	})
+	ws.Set("onmessage", func(e *js.Object) {
+		h.Receive(e.Get("data").String())
+	})
+	ws.Set("onclose", func() {
+		print("remote connection closed")
+	})
+}
+
+// DefaultRemoteURL is the websocket endpoint on the server that served this page
+func DefaultRemoteURL() string {
+	l := js.Global.Get("window").Get("location")
+	scheme := "ws://"
+	if l.Get("protocol").String() == "https:" {
+		scheme = "wss://"
+	}
+	return scheme + l.Get("host").String() + "/ws"
+}
+
+func startHost(send func(msg string)) *host {
+	h := &host{send: send}
+
+	// the handlers in Setup check these before the first output arrives
+	clickable = map[string]bool{}
+	doubleClickable = map[string]bool{}
+	hoverable = map[string]bool{}
+	keyupable = map[string]bool{}
+	keyupableCodes = map[string][]int{}
+	scrollable = map[string]bool{}
+	linkable = map[string]bool{}
+	shortcuts = map[string]bool{}
+	uploadable = map[string]bool{}
+
+	forwardInput = func(ev InputEvent) {
+		h.sendEvent(messageInput, ev)
+	}
+	layoutChanged = func() {
+		h.wanted = true
+		h.schedule()
+	}
+	Setup()
+	return h
+}
+
+func (h *host) sendEvent(messageType string, ev InputEvent) {
+	h.send(eventMessage(messageType, ev))
+}
+
+func (h *host) Receive(msg string) {
+	v, err := parseJSON(msg)
+	if err != nil {
+		print("invalid message", err.Error())
+		return
+	}
+
+	m, _ := v.(map[string]interface{})
+	switch jsonString(m, "type") {
+	case messageRequestFrame:
+		h.wanted = true
+		h.schedule()
+	case messageLocation:
+		browserBackend{}.SetLocation(jsonString(m, "location"))
+	case messageSave:
+		localStorage := js.Global.Get("localStorage")
+		if jsonBool(m, "remove") {
+			localStorage.Call("removeItem", jsonString(m, "key"))
+		} else {
+			localStorage.Call("setItem", jsonString(m, "key"), jsonString(m, "value"))
+		}
+	case messageOutput:
+		h.output(m)
+		h.waiting = false
+		h.schedule()
+	}
+}
+
+// storageMessage has everything in localStorage
+func storageMessage() string {
+	localStorage := js.Global.Get("localStorage")
+	items := map[string]string{}
+	for i := 0; i < localStorage.Length(); i++ {
+		key := localStorage.Call("key", i).String()
+		items[key] = localStorage.Call("getItem", key).String()
+	}
+
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageStorage)
+	w.Raw(`,"items":`)
+	w.StringMap(items)
+	w.Raw("}")
+	return w.Done()
+}
+
+func (h *host) schedule() {
+	if h.wanted && !h.waiting && !h.scheduled {
+		h.scheduled = true
+		js.Global.Get("window").Call("requestAnimationFrame", h.frame)
+	}
+}
+
+func (h *host) frame() {
+	h.scheduled = false
+	h.wanted = false
+	h.waiting = true
+
+	values, selection, ok := browserBackend{}.Snapshot(focusId)
+	location = browserBackend{}.Location()
+	ev := InputEvent{Type: eventFrame, Values: values, Selection: selection, HasSelection: ok, Location: location, Rects: browserBackend{}.Rects(), Now: appNow()}
+	record(ev)
+	h.sendEvent(messageFrame, ev)
+}
+
+func (h *host) output(m map[string]interface{}) {
+	patches, err := readPatches(m["patches"])
+	if err != nil {
+		print("invalid output", err.Error())
+		return
+	}
+
+	rendering = true
+
+	clickable = stringSet(jsonStrings(m["click"]))
+	doubleClickable = stringSet(jsonStrings(m["dblclick"]))
+	hoverable = stringSet(jsonStrings(m["hover"]))
+	scrollable = stringSet(jsonStrings(m["scroll"]))
+	linkable = stringSet(jsonStrings(m["link"]))
+	shortcuts = stringSet(jsonStrings(m["shortcut"]))
+	uploadable = stringSet(jsonStrings(m["file"]))
+	activeTrap = jsonString(m, "trap")
+	keyupable = map[string]bool{}
+	keyupableCodes = map[string][]int{}
+	keyup, _ := m["keyup"].(map[string]interface{})
+	for id, codes := range keyup {
+		keyupable[id] = true
+		keyupableCodes[id] = jsonInts(codes)
+	}
+
+	browserBackend{}.Patch(patches)
+	browserBackend{}.ScrollIntoView(jsonStrings(m["reveal"]))
+	browserBackend{}.Download(readFiles(m["download"]))
+	browserBackend{}.Notify(readNotifications(m["notify"]), jsonBool(m, "asknotify"))
+	browserBackend{}.Measure(jsonStrings(m["measure"]))
+
+	focusId = jsonString(m, "focus")
+	selection := [2]int{-1, -1}
+	if s := jsonInts(m["selection"]); len(s) == 2 {
+		selection = [2]int{s[0], s[1]}
+	}
+	browserBackend{}.Restore(jsonStringMap(m["values"]), focusId, selection)
+
+	rendering = false
+}
+
+func stringSet(a []string) map[string]bool {
+	set := map[string]bool{}
+	for _, s := range a {
+		set[s] = true
+	}
+	return set
+}
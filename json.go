@@ -0,0 +1,19 @@
+package main
+
+import "github.com/christopherhesse/todomvc-gopherjs-im/minjson"
+
+// json support lives in minjson so that todoio can use it too, these are the names the library uses for it
+
+type DecodeError = minjson.DecodeError
+type jsonWriter = minjson.Writer
+
+var (
+	parseJSON     = minjson.Parse
+	jsonString    = minjson.String
+	jsonInt       = minjson.Int
+	jsonBool      = minjson.Bool
+	jsonInts      = minjson.Ints
+	jsonStrings   = minjson.Strings
+	jsonStringMap = minjson.StringMap
+	itoa          = minjson.Itoa
+)
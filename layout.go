@@ -0,0 +1,134 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+// Rect is an element's border box in CSS pixels, relative to the viewport like getBoundingClientRect
+type Rect struct {
+	X, Y, Width, Height float64
+}
+
+var (
+	// ids passed to Measure during the current frame
+	measured map[string]bool
+
+	// what the backend measured at the end of the previous frame
+	rects = map[string]Rect{}
+
+	// called when a measured element changes size between frames, for instance because the window was resized
+	layoutChanged = Rerender
+
+	browserRects   = map[string]Rect{}
+	resizeObserver *js.Object
+)
+
+// Measure returns the rect id had at the end of the previous frame and keeps measuring it while it is asked for.
+// The first frame an element is asked for there is nothing to return, so expect one frame of lag.
+func Measure(id string) (Rect, bool) {
+	measured[id] = true
+	r, ok := rects[id]
+	return r, ok
+}
+
+func copyRects(r map[string]Rect) map[string]Rect {
+	c := map[string]Rect{}
+	for k, v := range r {
+		c[k] = v
+	}
+	return c
+}
+
+func (browserBackend) Measure(ids []string) {
+	browserRects = map[string]Rect{}
+	for _, id := range ids {
+		if r, ok := measureElement(id); ok {
+			browserRects[id] = r
+		}
+	}
+	observeResize(ids)
+}
+
+func (browserBackend) Rects() map[string]Rect {
+	return copyRects(browserRects)
+}
+
+func measureElement(id string) (Rect, bool) {
+	elem := js.Global.Get("document").Call("getElementById", id)
+	if elem == nil {
+		return Rect{}, false
+	}
+
+	r := elem.Call("getBoundingClientRect")
+	return Rect{X: r.Get("left").Float(), Y: r.Get("top").Float(), Width: r.Get("width").Float(), Height: r.Get("height").Float()}, true
+}
+
+// observeResize watches the measured elements so that a size change that doesn't come from a frame, like the window
+// being resized, still updates the cache
+func observeResize(ids []string) {
+	constructor := js.Global.Get("ResizeObserver")
+	if constructor == js.Undefined {
+		return
+	}
+
+	if resizeObserver == nil {
+		resizeObserver = constructor.New(func(entries *js.Object) {
+			// observing an element always reports it once, this only rerenders if something actually changed
+			changed := false
+			for i := 0; i < entries.Length(); i++ {
+				id := entries.Index(i).Get("target").Get("id").String()
+				if r, ok := measureElement(id); ok && r != browserRects[id] {
+					browserRects[id] = r
+					changed = true
+				}
+			}
+			if changed {
+				layoutChanged()
+			}
+		})
+	}
+
+	// patches may have replaced any of the elements, so start over each time
+	resizeObserver.Call("disconnect")
+	for _, id := range ids {
+		if elem := js.Global.Get("document").Call("getElementById", id); elem != nil {
+			resizeObserver.Call("observe", elem)
+		}
+	}
+}
+
+// rects are sent as {"id":[x,y,width,height]}
+func writeRects(w *jsonWriter, r map[string]Rect) {
+	w.Raw("{")
+	for i, id := range sortedKeys(r) {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.String(id)
+		w.Raw(":[")
+		w.Float(r[id].X)
+		w.Raw(",")
+		w.Float(r[id].Y)
+		w.Raw(",")
+		w.Float(r[id].Width)
+		w.Raw(",")
+		w.Float(r[id].Height)
+		w.Raw("]")
+	}
+	w.Raw("}")
+}
+
+func readRects(v interface{}) map[string]Rect {
+	m, _ := v.(map[string]interface{})
+	r := map[string]Rect{}
+	for id, e := range m {
+		a, _ := e.([]interface{})
+		if len(a) != 4 {
+			continue
+		}
+		f := [4]float64{}
+		for i := range f {
+			f[i], _ = a[i].(float64)
+		}
+		r[id] = Rect{X: f[0], Y: f[1], Width: f[2], Height: f[3]}
+	}
+	return r
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMeasure feeds the virtual list's measurements back in the way a host does, with the rects measured at the end
+// of the frame before
+func TestMeasure(t *testing.T) {
+	todos := []Todo{}
+	for i := 0; i < 100; i++ {
+		todos = append(todos, Todo{Id: i, Text: "todo " + itoa(i)})
+	}
+	a := newTestApp(t, todos)
+
+	rows := func(r map[string]Rect) int {
+		a.backend.Receive(eventMessage(messageFrame, InputEvent{Type: eventFrame, Values: map[string]string{}, Rects: r}))
+		return strings.Count(EncodeVNode(PreviousRoot), `"id":"todo-item-`)
+	}
+	measured := func(viewport float64, row float64) map[string]Rect {
+		r := map[string]Rect{"todo-list": {Width: 400, Height: viewport}}
+		for i := 0; i < 100; i++ {
+			r[todoItemId(i)] = Rect{Width: 400, Height: row}
+		}
+		return r
+	}
+
+	// nothing measured yet, the list is as high as it is allowed to be and rows have their estimated height of 58
+	if n := rows(nil); n != 16 {
+		t.Fatalf("%d rows before measuring", n)
+	}
+	if !strings.Contains(a.last(), `"todo-list"`) || !strings.Contains(a.last(), `"todo-item-15"`) {
+		t.Fatalf("the list and its rows weren't measured %s", a.last())
+	}
+
+	// rows measure 29, which places the rows from the next frame on
+	if n := rows(measured(580, 29)); n != 16 {
+		t.Fatalf("%d rows in the frame that measured them", n)
+	}
+	if n := rows(map[string]Rect{"todo-list": {Width: 400, Height: 580}}); n != 26 {
+		t.Fatalf("%d rows with rows measured before", n)
+	}
+
+	// the window gets smaller and the rows taller
+	if n := rows(measured(435, 58)); n != 21 {
+		t.Fatalf("%d rows after resizing", n)
+	}
+	if n := rows(measured(435, 58)); n != 13 {
+		t.Fatalf("%d rows once the new row height is in", n)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build js
+// +build js
+
+package main
+
+func main() {
+	switch {
+	case InWorker():
+		RunWorker()
+	case queryFlag("worker"):
+		StartWorker("main.js")
+	case queryFlag("remote"):
+		ConnectRemote(DefaultRemoteURL())
+	default:
+		Setup()
+		Rerender()
+	}
+}
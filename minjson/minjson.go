@@ -0,0 +1,477 @@
+// Package minjson is the small part of JSON that the library and todoio need, so that they don't pull in
+// encoding/json, which is large under gopherjs.
+package minjson
+
+type DecodeError string
+
+func (e DecodeError) Error() string {
+	return string(e)
+}
+
+type Writer struct {
+	buf []byte
+}
+
+func (w *Writer) Raw(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+func (w *Writer) String(s string) {
+	const hex = "0123456789abcdef"
+	w.buf = append(w.buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			w.buf = append(w.buf, '\\', c)
+		case c == '\n':
+			w.buf = append(w.buf, '\\', 'n')
+		case c == '\r':
+			w.buf = append(w.buf, '\\', 'r')
+		case c == '\t':
+			w.buf = append(w.buf, '\\', 't')
+		case c < 0x20:
+			w.buf = append(w.buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			w.buf = append(w.buf, c)
+		}
+	}
+	w.buf = append(w.buf, '"')
+}
+
+func (w *Writer) Int(i int) {
+	w.buf = append(w.buf, Itoa(i)...)
+}
+
+// Float writes f rounded to hundredths, which is plenty for pixels and milliseconds
+func (w *Writer) Float(f float64) {
+	if f < 0 {
+		w.Raw("-")
+		f = -f
+	}
+	hundredths := int(f*100 + 0.5)
+	w.Int(hundredths / 100)
+	if fraction := hundredths % 100; fraction != 0 {
+		w.Raw(".")
+		if fraction < 10 {
+			w.Raw("0")
+		}
+		if fraction%10 == 0 {
+			fraction /= 10
+		}
+		w.Int(fraction)
+	}
+}
+
+func (w *Writer) Bool(b bool) {
+	if b {
+		w.Raw("true")
+	} else {
+		w.Raw("false")
+	}
+}
+
+func (w *Writer) Ints(a []int) {
+	w.Raw("[")
+	for i, v := range a {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Int(v)
+	}
+	w.Raw("]")
+}
+
+func (w *Writer) Strings(a []string) {
+	w.Raw("[")
+	for i, v := range a {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.String(v)
+	}
+	w.Raw("]")
+}
+
+// StringMap writes keys in sorted order so that output is deterministic
+func (w *Writer) StringMap(m map[string]string) {
+	keys := sortedKeys(m)
+	w.Raw("{")
+	for i, k := range keys {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.String(k)
+		w.Raw(":")
+		w.String(m[k])
+	}
+	w.Raw("}")
+}
+
+func (w *Writer) Done() string {
+	return string(w.buf)
+}
+
+// Parse returns a tree of map[string]interface{}, []interface{}, string, float64, bool and nil
+func Parse(s string) (interface{}, error) {
+	p := &parser{s: s}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if p.pos != len(p.s) {
+		return nil, p.error("unexpected trailing data")
+	}
+	return v, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) error(msg string) error {
+	return DecodeError("json: " + msg + " at offset " + Itoa(p.pos))
+}
+
+func (p *parser) space() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) literal(word string) bool {
+	if len(p.s)-p.pos >= len(word) && p.s[p.pos:p.pos+len(word)] == word {
+		p.pos += len(word)
+		return true
+	}
+	return false
+}
+
+func (p *parser) value() (interface{}, error) {
+	p.space()
+	if p.pos >= len(p.s) {
+		return nil, p.error("unexpected end of input")
+	}
+
+	switch c := p.s[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		return p.string()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	case p.literal("true"):
+		return true, nil
+	case p.literal("false"):
+		return false, nil
+	case p.literal("null"):
+		return nil, nil
+	}
+	return nil, p.error("unexpected character")
+}
+
+func (p *parser) object() (interface{}, error) {
+	m := map[string]interface{}{}
+	p.pos++ // {
+	p.space()
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return m, nil
+	}
+
+	for {
+		p.space()
+		if p.pos >= len(p.s) || p.s[p.pos] != '"' {
+			return nil, p.error("expected object key")
+		}
+		k, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+
+		p.space()
+		if p.pos >= len(p.s) || p.s[p.pos] != ':' {
+			return nil, p.error("expected ':'")
+		}
+		p.pos++
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+
+		p.space()
+		if p.pos >= len(p.s) {
+			return nil, p.error("unexpected end of object")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return m, nil
+		default:
+			return nil, p.error("expected ',' or '}'")
+		}
+	}
+}
+
+func (p *parser) array() (interface{}, error) {
+	a := []interface{}{}
+	p.pos++ // [
+	p.space()
+	if p.pos < len(p.s) && p.s[p.pos] == ']' {
+		p.pos++
+		return a, nil
+	}
+
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+
+		p.space()
+		if p.pos >= len(p.s) {
+			return nil, p.error("unexpected end of array")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return a, nil
+		default:
+			return nil, p.error("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *parser) string() (string, error) {
+	p.pos++ // opening quote
+	buf := []byte{}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return string(buf), nil
+		case c == '\\':
+			if p.pos >= len(p.s) {
+				return "", p.error("unexpected end of string")
+			}
+			e := p.s[p.pos]
+			p.pos++
+			switch e {
+			case '"', '\\', '/':
+				buf = append(buf, e)
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'u':
+				r, ok := p.hex4()
+				if !ok {
+					return "", p.error("invalid unicode escape")
+				}
+				// combine surrogate pairs
+				if r >= 0xd800 && r < 0xdc00 && p.literal("\\u") {
+					r2, ok := p.hex4()
+					if !ok {
+						return "", p.error("invalid unicode escape")
+					}
+					if r2 >= 0xdc00 && r2 < 0xe000 {
+						r = 0x10000 + (r-0xd800)<<10 + (r2 - 0xdc00)
+					} else {
+						buf = append(buf, string(rune(r))...)
+						r = r2
+					}
+				}
+				buf = append(buf, string(rune(r))...)
+			default:
+				return "", p.error("invalid escape")
+			}
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return "", p.error("unterminated string")
+}
+
+func (p *parser) hex4() (int, bool) {
+	if len(p.s)-p.pos < 4 {
+		return 0, false
+	}
+	r := 0
+	for i := 0; i < 4; i++ {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c >= '0' && c <= '9':
+			r = r<<4 | int(c-'0')
+		case c >= 'a' && c <= 'f':
+			r = r<<4 | int(c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			r = r<<4 | int(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	return r, true
+}
+
+func (p *parser) number() (interface{}, error) {
+	negative := false
+	if p.s[p.pos] == '-' {
+		negative = true
+		p.pos++
+	}
+
+	digits := 0
+	v := 0.0
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		v = v*10 + float64(p.s[p.pos]-'0')
+		p.pos++
+		digits++
+	}
+	if digits == 0 {
+		return nil, p.error("invalid number")
+	}
+
+	if p.pos < len(p.s) && p.s[p.pos] == '.' {
+		p.pos++
+		scale := 0.1
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			v += float64(p.s[p.pos]-'0') * scale
+			scale /= 10
+			p.pos++
+		}
+	}
+
+	if p.pos < len(p.s) && (p.s[p.pos] == 'e' || p.s[p.pos] == 'E') {
+		p.pos++
+		negativeExp := false
+		if p.pos < len(p.s) && (p.s[p.pos] == '+' || p.s[p.pos] == '-') {
+			negativeExp = p.s[p.pos] == '-'
+			p.pos++
+		}
+		exp := 0
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			exp = exp*10 + int(p.s[p.pos]-'0')
+			p.pos++
+		}
+		for ; exp > 0; exp-- {
+			if negativeExp {
+				v /= 10
+			} else {
+				v *= 10
+			}
+		}
+	}
+
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// helpers for pulling typed values out of parsed json, missing or mistyped values are zero
+
+func String(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func Int(m map[string]interface{}, key string) int {
+	f, _ := m[key].(float64)
+	return int(f)
+}
+
+func Bool(m map[string]interface{}, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
+
+func Ints(v interface{}) []int {
+	a, _ := v.([]interface{})
+	ints := make([]int, 0, len(a))
+	for _, e := range a {
+		f, _ := e.(float64)
+		ints = append(ints, int(f))
+	}
+	return ints
+}
+
+func Strings(v interface{}) []string {
+	a, _ := v.([]interface{})
+	strs := make([]string, 0, len(a))
+	for _, e := range a {
+		s, _ := e.(string)
+		strs = append(strs, s)
+	}
+	return strs
+}
+
+func StringMap(v interface{}) map[string]string {
+	o, _ := v.(map[string]interface{})
+	m := map[string]string{}
+	for k, e := range o {
+		s, _ := e.(string)
+		m[k] = s
+	}
+	return m
+}
+
+// Itoa is strconv.Itoa without strconv, which the library leaves out of its gopherjs build
+func Itoa(i int) string {
+	if i == 0 {
+		return "0"
+	}
+
+	negative := i < 0
+	if negative {
+		i = -i
+	}
+
+	buf := [20]byte{}
+	pos := len(buf)
+	for i > 0 {
+		pos--
+		buf[pos] = byte('0' + i%10)
+		i /= 10
+	}
+	if negative {
+		pos--
+		buf[pos] = '-'
+	}
+	return string(buf[pos:])
+}
+
+// sortedKeys is an insertion sort, maps written here are small
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+		for i := len(keys) - 1; i > 0 && keys[i] < keys[i-1]; i-- {
+			keys[i], keys[i-1] = keys[i-1], keys[i]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,78 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+type Notification struct {
+	Title string
+	Body  string
+}
+
+var (
+	notifications = []Notification{}
+	askNotify     = false
+)
+
+// Notify shows a system notification once this frame is applied. Notifications are dropped unless the user allowed
+// them, see AskToNotify.
+func Notify(title string, body string) {
+	notifications = append(notifications, Notification{Title: title, Body: body})
+}
+
+// AskToNotify asks the user to allow notifications once this frame is applied, unless they were asked before.
+// Browsers only ask in response to the user, so call it in a frame that handles a click or a key, not in one that a
+// timer asked for.
+func AskToNotify() {
+	askNotify = true
+}
+
+func (browserBackend) Notify(notes []Notification, ask bool) {
+	constructor := js.Global.Get("Notification")
+	if constructor == js.Undefined || len(notes) == 0 && !ask {
+		return
+	}
+
+	show := func() {
+		for _, n := range notes {
+			constructor.New(n.Title, js.M{"body": n.Body})
+		}
+	}
+
+	switch constructor.Get("permission").String() {
+	case "granted":
+		show()
+	case "default":
+		if !ask {
+			return
+		}
+		constructor.Call("requestPermission").Call("then", func(permission string) {
+			if permission == "granted" {
+				show()
+			}
+		})
+	}
+}
+
+func writeNotifications(w *jsonWriter, notes []Notification) {
+	w.Raw("[")
+	for i, n := range notes {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Raw(`{"title":`)
+		w.String(n.Title)
+		w.Raw(`,"body":`)
+		w.String(n.Body)
+		w.Raw("}")
+	}
+	w.Raw("]")
+}
+
+func readNotifications(v interface{}) []Notification {
+	a, _ := v.([]interface{})
+	notes := []Notification{}
+	for _, e := range a {
+		m, _ := e.(map[string]interface{})
+		notes = append(notes, Notification{Title: jsonString(m, "title"), Body: jsonString(m, "body")})
+	}
+	return notes
+}
@@ -0,0 +1,81 @@
+package main
+
+const profileHistory = 120
+
+type FrameProfile struct {
+	Frame   int
+	StartMs float64
+
+	// time spent in each phase of the frame, render does not include diff and patch
+	InputMs   float64
+	RenderMs  float64
+	DiffMs    float64
+	PatchMs   float64
+	RestoreMs float64
+	TotalMs   float64
+
+	Patches         map[string]int
+	VNodesCreated   int
+	DOMNodesCreated int
+}
+
+var (
+	// print a summary of every frame to the console
+	Profiling = false
+
+	profiles     [profileHistory]FrameProfile
+	profileCount = 0
+
+	// profile for the frame in progress, nil outside of Frame
+	profile *FrameProfile
+)
+
+func now() float64 {
+	return backend.Now()
+}
+
+func beginProfile() *FrameProfile {
+	p := &profiles[profileCount%profileHistory]
+	*p = FrameProfile{
+		Frame:   profileCount,
+		StartMs: now(),
+		Patches: map[string]int{},
+	}
+	profile = p
+	return p
+}
+
+func endProfile(p *FrameProfile) {
+	p.TotalMs = now() - p.StartMs
+	profile = nil
+	profileCount++
+
+	if Profiling {
+		print("frame", p.Frame, "total", p.TotalMs, "ms",
+			"input", p.InputMs, "render", p.RenderMs, "diff", p.DiffMs, "patch", p.PatchMs, "restore", p.RestoreMs,
+			"vnodes", p.VNodesCreated, "dom", p.DOMNodesCreated,
+			"replace", p.Patches[patchReplace], "update", p.Patches[patchUpdate],
+			"remove", p.Patches[patchRemoveLastChild], "append", p.Patches[patchAppendChild])
+	}
+}
+
+// Profiles returns the most recent frame profiles, oldest first
+func Profiles() []FrameProfile {
+	n := profileCount
+	if n > profileHistory {
+		n = profileHistory
+	}
+
+	result := make([]FrameProfile, n)
+	for i := 0; i < n; i++ {
+		result[i] = profiles[(profileCount-n+i)%profileHistory]
+	}
+	return result
+}
+
+func LastProfile() (FrameProfile, bool) {
+	if profileCount == 0 {
+		return FrameProfile{}, false
+	}
+	return profiles[(profileCount-1)%profileHistory], true
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestProfile(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	// every reading of the clock is a millisecond after the one before
+	clock := 0.0
+	a.backend.now = func() float64 {
+		clock++
+		return clock
+	}
+
+	a.frame("", nil)
+	p, ok := LastProfile()
+	if !ok {
+		t.Fatal("no profile after a frame")
+	}
+	if p.InputMs <= 0 || p.RenderMs <= 0 || p.DiffMs <= 0 || p.RestoreMs <= 0 {
+		t.Fatalf("a phase of the frame wasn't timed %+v", p)
+	}
+	if p.TotalMs < p.InputMs+p.RenderMs+p.DiffMs+p.PatchMs+p.RestoreMs {
+		t.Fatalf("the phases take longer than the frame %+v", p)
+	}
+	// the first frame replaces the whole page
+	if p.Patches[patchReplace] != 1 || p.VNodesCreated == 0 {
+		t.Fatalf("the first frame's patches and vnodes weren't counted %+v", p)
+	}
+
+	// the todo is toggled while the first frame renders and shows as completed in the second
+	a.input(InputEvent{Type: eventClick, Id: "checkbox-todo-item-0"})
+	a.frame("", nil)
+	a.frame("", nil)
+	next, _ := LastProfile()
+	if next.Frame != p.Frame+2 || next.Patches[patchUpdate] == 0 {
+		t.Fatalf("the frame after got %+v", next)
+	}
+
+	// only the latest frames are kept, oldest first
+	for i := 0; i < profileHistory; i++ {
+		a.frame("", nil)
+	}
+	profiles := Profiles()
+	last, _ := LastProfile()
+	if len(profiles) != profileHistory || profiles[0].Frame != last.Frame-profileHistory+1 || profiles[profileHistory-1].Frame != last.Frame {
+		t.Fatalf("kept %d profiles from frame %d", len(profiles), profiles[0].Frame)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gopherjs/gopherjs/js"
+)
+
+const (
+	recordingVersion = 3
+
+	eventFrame       = "frame"
+	eventClick       = "click"
+	eventDoubleClick = "dblclick"
+	eventFocus       = "focus"
+	eventBlur        = "blur"
+	eventKeyup       = "keyup"
+	eventHover       = "hover"
+	eventScroll      = "scroll"
+	eventFocusMove   = "focus-move"
+	eventShortcut    = "shortcut"
+	eventFile        = "file"
+	eventLocation    = "location"
+	eventDebug       = "debug"
+)
+
+type InputEvent struct {
+	Type string
+	Id   string
+	Ids  []string
+	Code int // key code, or the direction for focus-move
+
+	// used by scroll events, the element's scrollTop
+	Scroll float64
+
+	// used by file events, the file that was chosen
+	File File
+
+	// used by frame events, this is what the backend reported at the start of the frame
+	Values       map[string]string
+	Selection    [2]int
+	HasSelection bool
+	Location     string
+	Rects        map[string]Rect
+	// the app's clock, which render reads for things like due dates
+	Now time.Time
+}
+
+type Recording struct {
+	Version int
+	// the app's state when recording started, see saveAppState
+	State  string
+	Events []InputEvent
+}
+
+var recording *Recording
+
+// StartRecording records from the current state on. The ui state that carries over between frames, what has focus,
+// what is hovered and how far things are scrolled, goes in as the first events. When frames are rendered somewhere
+// else events are recorded where they come in, but the state is this side's, which is what the other side started
+// from only if recording starts along with the page, as it does with ?record.
+func StartRecording() {
+	recording = &Recording{Version: recordingVersion, State: saveAppState()}
+	if focusId != "" {
+		record(InputEvent{Type: eventFocus, Id: focusId})
+	}
+	if len(hoverIds) > 0 {
+		record(InputEvent{Type: eventHover, Ids: hoverIds})
+	}
+	for _, id := range sortedKeys(scrollTops) {
+		record(InputEvent{Type: eventScroll, Id: id, Scroll: scrollTops[id]})
+	}
+}
+
+func StopRecording() *Recording {
+	r := recording
+	recording = nil
+	return r
+}
+
+func record(ev InputEvent) {
+	if recording != nil {
+		recording.Events = append(recording.Events, ev)
+	}
+}
+
+func copyValues(values map[string]string) map[string]string {
+	c := map[string]string{}
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// Replay starts from the state the recording started in and runs the recorded events through Frame, returning the
+// tree produced by each frame. Each frame is rendered at the time it was recorded at. Patches are applied to out, which can be nil to replay without a DOM, in which case
+// the app and the ui are put back the way they were afterwards.
+func Replay(r *Recording, out Backend) ([]*VNode, error) {
+	saved := saveUIState()
+	savedPooling := Pooling
+	replay := &replayBackend{out: out}
+	state := newUIState(replay)
+	if out != nil {
+		// the patches have to apply to what out is showing
+		state.previousRoot = saved.previousRoot
+		state.commits = saved.commits
+	}
+	loadUIState(state)
+
+	restoreApp, err := loadAppState(r.State)
+	if err != nil {
+		loadUIState(saved)
+		return nil, err
+	}
+	restoreClock := useRecordedClock(func() time.Time {
+		return replay.frame.Now
+	})
+	// the returned trees have to outlive the frames after them
+	Pooling = false
+
+	defer func() {
+		Pooling = savedPooling
+		restoreClock()
+		if out == nil {
+			restoreApp()
+			loadUIState(saved)
+			return
+		}
+		// the last replayed tree is what is now displayed, so keep the ui state that goes with it
+		backend = saved.backend
+		recording = saved.recording
+		DebugOverlay = saved.debugOverlay
+	}()
+
+	roots := []*VNode{}
+	for _, ev := range r.Events {
+		if ev.Type == eventFrame {
+			replay.frame = ev
+			Frame()
+			roots = append(roots, PreviousRoot)
+		} else {
+			applyInput(ev)
+		}
+	}
+	return roots, nil
+}
+
+// replayBackend reads input from the recording instead of the DOM
+type replayBackend struct {
+	frame InputEvent
+	out   Backend
+}
+
+func (b *replayBackend) Snapshot(focusId string) (map[string]string, [2]int, bool) {
+	return copyValues(b.frame.Values), b.frame.Selection, b.frame.HasSelection
+}
+
+func (b *replayBackend) Patch(patches []Patch) {
+	if b.out != nil {
+		b.out.Patch(patches)
+	}
+}
+
+func (b *replayBackend) Restore(values map[string]string, focusId string, selection [2]int) {
+	if b.out != nil {
+		b.out.Restore(values, focusId, selection)
+	}
+}
+
+func (b *replayBackend) ScrollIntoView(ids []string) {
+	if b.out != nil {
+		b.out.ScrollIntoView(ids)
+	}
+}
+
+// a replay shouldn't download anything or notify again
+func (b *replayBackend) Download(files []File) {}
+
+func (b *replayBackend) Notify(notes []Notification, ask bool) {}
+
+func (b *replayBackend) Measure(ids []string) {
+	if b.out != nil {
+		b.out.Measure(ids)
+	}
+}
+
+func (b *replayBackend) Rects() map[string]Rect {
+	return copyRects(b.frame.Rects)
+}
+
+func (b *replayBackend) Location() string {
+	return b.frame.Location
+}
+
+func (b *replayBackend) SetLocation(location string) {}
+
+// frames are driven by the recording
+func (b *replayBackend) RequestFrame() {}
+
+func (b *replayBackend) Flush() {
+	if b.out != nil {
+		b.out.Flush()
+	}
+}
+
+func (b *replayBackend) Now() float64 {
+	if b.out != nil {
+		return b.out.Now()
+	}
+	return 0
+}
+
+func (r *Recording) Encode() string {
+	w := &jsonWriter{}
+	w.Raw(`{"version":`)
+	w.Int(r.Version)
+	w.Raw(`,"state":`)
+	w.String(r.State)
+	w.Raw(`,"events":[`)
+	for i, ev := range r.Events {
+		if i > 0 {
+			w.Raw(",")
+		}
+		writeInputEvent(w, ev)
+	}
+	w.Raw("]}")
+	return w.Done()
+}
+
+func writeInputEvent(w *jsonWriter, ev InputEvent) {
+	w.Raw(`{"type":`)
+	w.String(ev.Type)
+	if ev.Id != "" {
+		w.Raw(`,"id":`)
+		w.String(ev.Id)
+	}
+	if ev.Type == eventHover {
+		w.Raw(`,"ids":`)
+		w.Strings(ev.Ids)
+	}
+	if ev.Code != 0 {
+		w.Raw(`,"code":`)
+		w.Int(ev.Code)
+	}
+	if ev.Type == eventScroll {
+		w.Raw(`,"scroll":`)
+		w.Float(ev.Scroll)
+	}
+	if ev.Type == eventFile {
+		w.Raw(`,"file":`)
+		writeFile(w, ev.File)
+	}
+	if ev.Type == eventFrame {
+		w.Raw(`,"values":`)
+		w.StringMap(ev.Values)
+		if ev.HasSelection {
+			w.Raw(`,"selection":`)
+			w.Ints(ev.Selection[:])
+		}
+		w.Raw(`,"location":`)
+		w.String(ev.Location)
+		if len(ev.Rects) > 0 {
+			w.Raw(`,"rects":`)
+			writeRects(w, ev.Rects)
+		}
+		w.Raw(`,"now":`)
+		w.String(ev.Now.Format(time.RFC3339Nano))
+	}
+	w.Raw("}")
+}
+
+func DecodeRecording(s string) (*Recording, error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, DecodeError("recording: expected object")
+	}
+
+	r := &Recording{Version: jsonInt(m, "version"), State: jsonString(m, "state")}
+	if r.Version != recordingVersion {
+		return nil, DecodeError("recording: unsupported version " + itoa(r.Version))
+	}
+
+	events, _ := m["events"].([]interface{})
+	for _, e := range events {
+		ev, err := readInputEvent(e)
+		if err != nil {
+			return nil, err
+		}
+		r.Events = append(r.Events, ev)
+	}
+	return r, nil
+}
+
+func readInputEvent(v interface{}) (InputEvent, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return InputEvent{}, DecodeError("input event: expected object")
+	}
+
+	ev := InputEvent{
+		Type:     jsonString(m, "type"),
+		Id:       jsonString(m, "id"),
+		Ids:      jsonStrings(m["ids"]),
+		Code:     jsonInt(m, "code"),
+		Location: jsonString(m, "location"),
+	}
+	ev.Scroll, _ = m["scroll"].(float64)
+	if ev.Type == eventFile {
+		ev.File = readFile(m["file"])
+	}
+	if ev.Type == eventFrame {
+		ev.Values = jsonStringMap(m["values"])
+		if selection := jsonInts(m["selection"]); len(selection) == 2 {
+			ev.Selection = [2]int{selection[0], selection[1]}
+			ev.HasSelection = true
+		}
+		ev.Rects = readRects(m["rects"])
+		now, err := time.Parse(time.RFC3339Nano, jsonString(m, "now"))
+		if err != nil {
+			return InputEvent{}, DecodeError("input event: invalid now")
+		}
+		ev.Now = now
+	}
+	return ev, nil
+}
+
+// exposeRecorder makes the recorder available from the devtools console so that users can attach a log to bug reports,
+// loading the page with ?record starts recording immediately
+func exposeRecorder() {
+	if queryFlag("record") {
+		StartRecording()
+	}
+
+	js.Global.Set("recorder", js.M{
+		"start": StartRecording,
+		"stop": func() string {
+			r := StopRecording()
+			if r == nil {
+				return ""
+			}
+			return r.Encode()
+		},
+		"replay": func(log string) string {
+			r, err := DecodeRecording(log)
+			if err != nil {
+				return err.Error()
+			}
+			if _, err := Replay(r, backend); err != nil {
+				return err.Error()
+			}
+			return ""
+		},
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	clock := useManualTimers(t, start)
+	// due tomorrow, when the recording was made
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}, {Id: 1, Text: "b", Due: duedate.Day(start.AddDate(0, 0, 1))}})
+	a.frame("", nil)
+
+	// the replay has to start from here, with undo history and focus the recording doesn't show being made
+	a.input(InputEvent{Type: eventClick, Id: "destroy-todo-item-0"})
+	a.frame("", nil)
+	a.input(InputEvent{Type: eventFocus, Id: "new-todo"})
+	a.frame("", nil)
+
+	StartRecording()
+	live := []string{}
+	frame := func(values map[string]string) {
+		a.frame("", values)
+		live = append(live, EncodeVNode(PreviousRoot))
+	}
+	a.input(InputEvent{Type: eventKeyup, Id: "new-todo", Code: keyEnter})
+	frame(map[string]string{"new-todo": "c tomorrow"})
+	frame(nil)
+	a.input(InputEvent{Type: eventClick, Id: "destroy-todo-item-1"})
+	frame(nil)
+	// the last undo is of the delete from before recording
+	for i := 0; i < 3; i++ {
+		a.input(InputEvent{Type: eventShortcut, Id: "Ctrl+z"})
+		frame(nil)
+	}
+	if len(store.State().Lists[0].Todos) != 2 {
+		t.Fatal("undo didn't bring back the todo deleted before recording")
+	}
+	r, err := DecodeRecording(StopRecording().Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// somewhere else entirely, on another day
+	clock.advance(48 * time.Hour)
+	store = newStore(nil)
+	undoStack, redoStack, toast = nil, nil, ""
+	a.input(InputEvent{Type: eventBlur})
+	a.frame("", nil)
+	before := EncodeVNode(PreviousRoot)
+
+	for i := 0; i < 2; i++ {
+		roots, err := Replay(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roots) != len(live) {
+			t.Fatalf("replay %d has %d frames, want %d", i, len(roots), len(live))
+		}
+		for j, root := range roots {
+			if got := EncodeVNode(root); got != live[j] {
+				t.Errorf("replay %d frame %d differs\ngot  %s\nwant %s", i, j, got, live[j])
+			}
+		}
+	}
+
+	if EncodeVNode(PreviousRoot) != before || len(store.State().Lists[0].Todos) != 3 || focusId != "" {
+		t.Fatal("replay didn't put the app back")
+	}
+}
+
+func TestReplayBadState(t *testing.T) {
+	if _, err := Replay(&Recording{Version: recordingVersion, State: "[]"}, nil); err == nil {
+		t.Fatal("replayed a recording without state")
+	}
+}
+
+// TestRecordForwarded records input that is rendered somewhere else, as with ?worker&record
+func TestRecordForwarded(t *testing.T) {
+	newTestApp(t, nil)
+	forwarded := []InputEvent{}
+	forwardInput = func(ev InputEvent) {
+		forwarded = append(forwarded, ev)
+	}
+	defer func() { forwardInput = nil }()
+
+	StartRecording()
+	Input(InputEvent{Type: eventClick, Id: "toggle-all"})
+	r := StopRecording()
+	if len(forwarded) != 1 || len(r.Events) != 1 || r.Events[0].Id != "toggle-all" {
+		t.Fatalf("forwarded %v and recorded %v", forwarded, r.Events)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+var (
+	// number of frames committed so far, refs use it to tell whether their vnode is still on screen
+	commits = 0
+
+	afterPatch []func()
+)
+
+// Ref is a handle to the DOM element of a widget, the zero value is ready to use
+//
+// Like everything else it has to be bound again every frame the widget is drawn, once a frame is committed
+// without it Element returns nil.
+type Ref struct {
+	vnode VNodeHandle
+	frame int
+
+	// the binding from the frame before, so Element still works after Bind has been called this frame
+	previous      VNodeHandle
+	previousFrame int
+}
+
+// Bind attaches the ref to the element currently being drawn, call it from inside the element's function
+func (r *Ref) Bind() {
+	if Active == nil {
+		panic("Ref.Bind called outside of render")
+	}
+
+	if r.vnode != (VNodeHandle{}) && r.frame != commits {
+		r.previous = r.vnode
+		r.previousFrame = r.frame
+	}
+	r.vnode = HandleOf(Active)
+	r.frame = commits
+}
+
+// Element returns the DOM node from the most recently committed frame, nil if the widget wasn't drawn in that
+// frame or the DOM isn't on this side, as when rendering in a web worker or on the server
+func (r *Ref) Element() *js.Object {
+	switch {
+	case r.vnode != (VNodeHandle{}) && r.frame == commits-1:
+		return domOf(r.vnode.Get())
+	case r.previous != (VNodeHandle{}) && r.previousFrame == commits-1:
+		return domOf(r.previous.Get())
+	}
+	return nil
+}
+
+// AfterPatch runs f once the current frame has been applied, which is the earliest point at which refs bound
+// during the frame resolve and layout can be measured
+func AfterPatch(f func()) {
+	afterPatch = append(afterPatch, f)
+}
+
+func runAfterPatch() {
+	// callbacks may register more callbacks, those run with the next frame
+	callbacks := afterPatch
+	afterPatch = nil
+	for _, f := range callbacks {
+		f()
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gopherjs/gopherjs/js"
+)
+
+func TestRef(t *testing.T) {
+	newTestApp(t, nil)
+	var r Ref
+	frame := func(bind bool) *VNode {
+		root := tree(func() {
+			Div(func() {
+				Id("measured")
+				if bind {
+					r.Bind()
+				}
+			})
+		})
+		Commit(root)
+		return root
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Bind worked outside of render")
+			}
+		}()
+		r.Bind()
+	}()
+
+	root := frame(true)
+	// as PatchDOM would have
+	element := &js.Object{}
+	root.Children[0].dom = element
+	if r.Element() != element {
+		t.Fatal("the ref doesn't resolve to the element it was bound to")
+	}
+
+	// while the next frame is drawn, Element is still the one on screen
+	Init("body")
+	Div(func() {
+		r.Bind()
+		if r.Element() != element {
+			t.Fatal("binding again lost the element on screen")
+		}
+	})
+	Commit(Done())
+
+	frame(false)
+	if r.Element() != nil {
+		t.Fatal("the ref resolves after a frame without its widget")
+	}
+}
+
+func TestAfterPatch(t *testing.T) {
+	newTestApp(t, nil)
+	ran := []string{}
+	tree(func() {
+		AfterPatch(func() {
+			ran = append(ran, "first")
+			AfterPatch(func() {
+				ran = append(ran, "second")
+			})
+		})
+	})
+	if len(ran) != 0 {
+		t.Fatal("ran before the frame was committed")
+	}
+
+	Commit(tree(func() {}))
+	if len(ran) != 1 {
+		t.Fatalf("ran %v after the first commit", ran)
+	}
+	Commit(tree(func() {}))
+	if len(ran) != 2 || ran[1] != "second" {
+		t.Fatalf("ran %v after the second commit", ran)
+	}
+}
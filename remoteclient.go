@@ -0,0 +1,109 @@
+//go:build !js
+// +build !js
+
+package main
+
+import "errors"
+
+// RemoteClient stands in for a browser connected to RemoteHandler, it applies patches to a vnode tree
+// instead of the DOM so that server mode can be exercised in-process
+type RemoteClient struct {
+	conn    *wsConn
+	waiting bool // a frame was sent and its output has not arrived
+	wanted  bool // the server asked for another frame while waiting
+
+	Root     *VNode
+	Values   map[string]string
+	Focus    string
+	Location string
+
+	// there is no layout to measure here, so Rects is what gets reported for the ids in Measured
+	Rects    map[string]Rect
+	Measured []string
+
+	// every file the server has offered with Download and every notification it has shown
+	Downloads     []File
+	Notifications []Notification
+}
+
+func DialRemote(url string) (*RemoteClient, error) {
+	conn, err := dialWebSocket(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteClient{conn: conn, Values: map[string]string{}, Rects: map[string]Rect{}}, nil
+}
+
+func (c *RemoteClient) Input(ev InputEvent) error {
+	return c.conn.WriteMessage(eventMessage(messageInput, ev))
+}
+
+// Sync answers the server's frame requests until the output of the last requested frame has been applied.
+// Like the browser host, only one frame is in flight at a time.
+func (c *RemoteClient) Sync() error {
+	for {
+		msg, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		v, err := parseJSON(msg)
+		if err != nil {
+			return err
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return errors.New("remote client: expected message object")
+		}
+
+		switch jsonString(m, "type") {
+		case messageRequestFrame:
+			if c.waiting {
+				c.wanted = true
+				continue
+			}
+			if err := c.sendFrame(); err != nil {
+				return err
+			}
+		case messageLocation:
+			c.Location = jsonString(m, "location")
+		case messageOutput:
+			// decoding takes vnodes from the pool and hashes them in scratch space that sessions share, so it
+			// can't run while a session renders
+			sessionLock.Lock()
+			patches, err := readPatches(m["patches"])
+			if err == nil {
+				c.Root = PatchVNode(c.Root, patches)
+			}
+			sessionLock.Unlock()
+			if err != nil {
+				return err
+			}
+			c.Values = jsonStringMap(m["values"])
+			c.Focus = jsonString(m, "focus")
+			c.Measured = jsonStrings(m["measure"])
+			c.Downloads = append(c.Downloads, readFiles(m["download"])...)
+			c.Notifications = append(c.Notifications, readNotifications(m["notify"])...)
+			c.waiting = false
+
+			if !c.wanted {
+				return nil
+			}
+			c.wanted = false
+			if err := c.sendFrame(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *RemoteClient) sendFrame() error {
+	c.waiting = true
+	ev := InputEvent{Type: eventFrame, Values: copyValues(c.Values), Location: c.Location, Rects: copyRects(c.Rects)}
+	return c.conn.WriteMessage(eventMessage(messageFrame, ev))
+}
+
+func (c *RemoteClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,117 @@
+package main
+
+type RouterMode int
+
+const (
+	// paths live in the fragment, as in index.html#/active, which works with any file server
+	HashRouting RouterMode = iota
+	// paths are real urls, as in /active, the server has to answer every route with the app
+	HistoryRouting
+)
+
+// Routing has to be set before Setup
+var Routing = HashRouting
+
+// Path is the current route path, such as "/active", without the fragment marker or query string
+func Path() string {
+	path := location
+	if Routing == HashRouting && len(path) > 0 && path[0] == '#' {
+		path = path[1:]
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '?' {
+			path = path[:i]
+			break
+		}
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// Route matches the current path against pattern and returns its parameters. Segments starting with ':' match any
+// single segment and are returned under the rest of their name, a final '*' matches whatever is left and is returned as "*".
+// For example "/lists/:id/*" matches "/lists/4/active/x" with {"id": "4", "*": "active/x"}.
+func Route(pattern string) (map[string]string, bool) {
+	segments := splitPath(Path())
+	patterns := splitPath(pattern)
+	params := map[string]string{}
+	for i, p := range patterns {
+		switch {
+		case p == "*":
+			rest := ""
+			for j, s := range segments[i:] {
+				if j > 0 {
+					rest += "/"
+				}
+				rest += s
+			}
+			params["*"] = rest
+			return params, true
+		case i >= len(segments):
+			return nil, false
+		case p[0] == ':':
+			params[p[1:]] = segments[i]
+		case p != segments[i]:
+			return nil, false
+		}
+	}
+
+	if len(segments) != len(patterns) {
+		return nil, false
+	}
+	return params, true
+}
+
+func splitPath(path string) []string {
+	segments := []string{}
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '/' {
+			if i > start {
+				segments = append(segments, path[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return segments
+}
+
+// Href returns the url for path in the current routing mode
+func Href(path string) string {
+	if Routing == HashRouting {
+		return "#" + path
+	}
+	return path
+}
+
+// Navigate changes the path, adding a history entry, and renders another frame
+func Navigate(path string) {
+	SetLocation(Href(path))
+	Rerender()
+}
+
+// Link draws an <a> that navigates to path and reports whether it was clicked. arg is the content, as with Tag.
+// Plain left clicks are handled here, clicks with a modifier key are left to the browser so that opening in a new
+// tab and the like keep working.
+func Link(id string, path string, arg interface{}) bool {
+	Begin("a")
+	Id(id)
+	Attr("href", Href(path))
+	linkable[id] = true
+	clicked := Clicked(id)
+	drawContent(arg, "Link")
+	End("a")
+
+	if clicked {
+		Navigate(path)
+	}
+	return clicked
+}
+
+// browserOpensLink reports whether a click on a link is one the browser handles itself, like a middle click or a ctrl
+// click opening it in a new tab, rather than one Link navigates on
+func browserOpensLink(button int, ctrl, meta, shift, alt bool) bool {
+	return button != 0 || ctrl || meta || shift || alt
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}, {Id: 1, Text: "b", Completed: true}})
+	a.frame("#/lists/0/all", nil)
+
+	link := findVNode(PreviousRoot, "filter-active")
+	if link == nil {
+		t.Fatal("there's no filter link")
+	}
+	if link.Tag != "a" || link.Attributes["href"] != "#/lists/0/active" {
+		t.Fatalf("the filter link is %s", EncodeVNode(link))
+	}
+	if !linkable["filter-active"] || !clickable["filter-active"] || !strings.Contains(a.last(), `"link":["filter-active",`) {
+		t.Fatalf("the filter link isn't registered as a link %s", a.last())
+	}
+
+	// a plain click navigates without the browser following the href
+	a.input(InputEvent{Type: eventClick, Id: "filter-active"})
+	a.frame("", nil)
+	if location != "#/lists/0/active" {
+		t.Fatalf("the click went to %q", location)
+	}
+	// as the host does once it has been told the new location
+	a.frame(location, nil)
+	if findVNode(PreviousRoot, "todo-item-0") == nil || findVNode(PreviousRoot, "todo-item-1") != nil {
+		t.Fatal("the active filter doesn't show only the active todo")
+	}
+
+	savedRouting := Routing
+	Routing = HistoryRouting
+	defer func() { Routing = savedRouting }()
+	if got := Href("/lists/0/active"); got != "/lists/0/active" {
+		t.Fatalf("history routing links to %q", got)
+	}
+}
+
+func TestBrowserOpensLink(t *testing.T) {
+	for _, c := range []struct {
+		button                 int
+		ctrl, meta, shift, alt bool
+		want                   bool
+	}{
+		{0, false, false, false, false, false},
+		// middle click
+		{1, false, false, false, false, true},
+		// new tab, new tab on a mac, new window and download
+		{0, true, false, false, false, true},
+		{0, false, true, false, false, true},
+		{0, false, false, true, false, true},
+		{0, false, false, false, true, true},
+	} {
+		if got := browserOpensLink(c.button, c.ctrl, c.meta, c.shift, c.alt); got != c.want {
+			t.Errorf("%+v: got %v", c, got)
+		}
+	}
+}
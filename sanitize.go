@@ -0,0 +1,487 @@
+package main
+
+// SanitizePolicy is an allowlist, anything it doesn't mention is removed. Disallowed tags are dropped but their text is kept,
+// except for tags like script whose content is never text.
+type SanitizePolicy struct {
+	// allowed tags and the attributes allowed on each of them
+	Tags             map[string][]string
+	GlobalAttributes []string
+	// attributes that hold urls, these must be relative or use one of URLSchemes
+	URLAttributes []string
+	URLSchemes    []string
+}
+
+var (
+	DefaultPolicy = &SanitizePolicy{
+		Tags: map[string][]string{
+			"a":          {"href"},
+			"abbr":       nil,
+			"b":          nil,
+			"blockquote": nil,
+			"br":         nil,
+			"code":       nil,
+			"del":        nil,
+			"div":        nil,
+			"em":         nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "width", "height"},
+			"ins":        nil,
+			"kbd":        nil,
+			"li":         nil,
+			"ol":         {"start"},
+			"p":          nil,
+			"pre":        nil,
+			"q":          nil,
+			"s":          nil,
+			"small":      nil,
+			"span":       nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"colspan", "rowspan"},
+			"tfoot":      nil,
+			"th":         {"colspan", "rowspan"},
+			"thead":      nil,
+			"tr":         nil,
+			"u":          nil,
+			"ul":         nil,
+		},
+		GlobalAttributes: []string{"title"},
+		URLAttributes:    []string{"href", "src"},
+		URLSchemes:       []string{"http", "https", "mailto"},
+	}
+
+	voidTags = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+
+	// the content of these is never shown as text, so it goes along with the tag
+	dropContentTags = []string{"script", "style", "iframe", "object", "embed", "template", "noscript", "textarea", "title", "xmp", "noembed", "noframes", "plaintext", "svg", "math"}
+
+	namedEntities = map[string]string{
+		"amp;":     "&",
+		"lt;":      "<",
+		"gt;":      ">",
+		"quot;":    "\"",
+		"apos;":    "'",
+		"colon;":   ":",
+		"Tab;":     "\t",
+		"NewLine;": "\n",
+	}
+)
+
+// SafeRaw inserts html after removing everything DefaultPolicy doesn't allow, use it for anything that came from a user
+func SafeRaw(html string) {
+	UnsafeRaw(SanitizeHTML(html, DefaultPolicy))
+}
+
+func SanitizeHTML(html string, policy *SanitizePolicy) string {
+	s := &sanitizer{policy: policy, in: html}
+	s.run()
+	return string(s.out)
+}
+
+type sanitizer struct {
+	policy *SanitizePolicy
+	in     string
+	pos    int
+	out    []byte
+	open   []string
+}
+
+type htmlAttribute struct {
+	name  string
+	value string
+}
+
+func (s *sanitizer) run() {
+	for s.pos < len(s.in) {
+		switch c := s.in[s.pos]; c {
+		case '<':
+			s.markup()
+		case '>':
+			s.out = append(s.out, "&gt;"...)
+			s.pos++
+		case '&':
+			s.entity()
+		default:
+			s.out = append(s.out, c)
+			s.pos++
+		}
+	}
+
+	for i := len(s.open) - 1; i >= 0; i-- {
+		s.out = append(s.out, "</"+s.open[i]+">"...)
+	}
+}
+
+// entity copies a character reference through unchanged, anything else starting with & is escaped
+func (s *sanitizer) entity() {
+	i := s.pos + 1
+	if i < len(s.in) && s.in[i] == '#' {
+		i++
+		if i < len(s.in) && (s.in[i] == 'x' || s.in[i] == 'X') {
+			i++
+		}
+	}
+	start := i
+	for i < len(s.in) && isAlphanumeric(s.in[i]) {
+		i++
+	}
+
+	if i > start && i < len(s.in) && s.in[i] == ';' {
+		s.out = append(s.out, s.in[s.pos:i+1]...)
+		s.pos = i + 1
+		return
+	}
+
+	s.out = append(s.out, "&amp;"...)
+	s.pos++
+}
+
+func (s *sanitizer) markup() {
+	rest := s.in[s.pos:]
+	switch {
+	case hasPrefix(rest, "<!--"):
+		s.skipPast("-->")
+	case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?'):
+		s.skipPast(">")
+	case len(rest) > 1 && rest[1] == '/' && len(rest) > 2 && isLetter(rest[2]):
+		s.endTag()
+	case len(rest) > 1 && isLetter(rest[1]):
+		s.startTag()
+	default:
+		s.out = append(s.out, "&lt;"...)
+		s.pos++
+	}
+}
+
+func (s *sanitizer) skipPast(marker string) {
+	i := indexOf(s.in[s.pos:], marker)
+	if i < 0 {
+		s.pos = len(s.in)
+		return
+	}
+	s.pos += i + len(marker)
+}
+
+func (s *sanitizer) tagName() string {
+	start := s.pos
+	for s.pos < len(s.in) && (isAlphanumeric(s.in[s.pos]) || s.in[s.pos] == '-' || s.in[s.pos] == ':') {
+		s.pos++
+	}
+	return toLower(s.in[start:s.pos])
+}
+
+func (s *sanitizer) startTag() {
+	s.pos++ // <
+	name := s.tagName()
+
+	attributes := []htmlAttribute{}
+	closed := false
+	for s.pos < len(s.in) {
+		c := s.in[s.pos]
+		if c == '>' {
+			s.pos++
+			closed = true
+			break
+		}
+		if isSpace(c) || c == '/' {
+			s.pos++
+			continue
+		}
+
+		start := s.pos
+		for s.pos < len(s.in) && !isSpace(s.in[s.pos]) && s.in[s.pos] != '=' && s.in[s.pos] != '>' && s.in[s.pos] != '/' {
+			s.pos++
+		}
+		attribute := htmlAttribute{name: toLower(s.in[start:s.pos])}
+
+		s.skipSpace()
+		if s.pos < len(s.in) && s.in[s.pos] == '=' {
+			s.pos++
+			s.skipSpace()
+			attribute.value = s.attributeValue()
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	if !closed {
+		// unterminated tag at the end of the input
+		return
+	}
+
+	if contains(dropContentTags, name) {
+		s.skipContent(name)
+		return
+	}
+
+	allowed, ok := s.policy.Tags[name]
+	if !ok {
+		return
+	}
+
+	s.out = append(s.out, '<')
+	s.out = append(s.out, name...)
+	seen := []string{}
+	for _, attribute := range attributes {
+		// browsers use the first of duplicate attributes
+		if contains(seen, attribute.name) {
+			continue
+		}
+		seen = append(seen, attribute.name)
+
+		if !contains(allowed, attribute.name) && !contains(s.policy.GlobalAttributes, attribute.name) {
+			continue
+		}
+
+		value := decodeEntities(attribute.value)
+		if contains(s.policy.URLAttributes, attribute.name) && !s.safeURL(value) {
+			continue
+		}
+
+		s.out = append(s.out, ' ')
+		s.out = append(s.out, attribute.name...)
+		s.out = append(s.out, '=', '"')
+		s.out = append(s.out, escapeAttribute(value)...)
+		s.out = append(s.out, '"')
+	}
+	s.out = append(s.out, '>')
+
+	if !contains(voidTags, name) {
+		s.open = append(s.open, name)
+	}
+}
+
+func (s *sanitizer) skipSpace() {
+	for s.pos < len(s.in) && isSpace(s.in[s.pos]) {
+		s.pos++
+	}
+}
+
+func (s *sanitizer) attributeValue() string {
+	if s.pos >= len(s.in) {
+		return ""
+	}
+
+	if quote := s.in[s.pos]; quote == '"' || quote == '\'' {
+		s.pos++
+		start := s.pos
+		for s.pos < len(s.in) && s.in[s.pos] != quote {
+			s.pos++
+		}
+		value := s.in[start:s.pos]
+		if s.pos < len(s.in) {
+			s.pos++
+		}
+		return value
+	}
+
+	start := s.pos
+	for s.pos < len(s.in) && !isSpace(s.in[s.pos]) && s.in[s.pos] != '>' {
+		s.pos++
+	}
+	return s.in[start:s.pos]
+}
+
+// skipContent drops everything up to and including the end tag for name
+func (s *sanitizer) skipContent(name string) {
+	for {
+		i := indexOf(s.in[s.pos:], "</")
+		if i < 0 {
+			s.pos = len(s.in)
+			return
+		}
+		s.pos += i + 2
+		if hasPrefix(toLower(s.in[s.pos:]), name) {
+			s.skipPast(">")
+			return
+		}
+	}
+}
+
+func (s *sanitizer) endTag() {
+	s.pos += 2 // </
+	name := s.tagName()
+	s.skipPast(">")
+
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] == name {
+			// close anything left open inside of it too
+			for j := len(s.open) - 1; j >= i; j-- {
+				s.out = append(s.out, "</"+s.open[j]+">"...)
+			}
+			s.open = s.open[:i]
+			return
+		}
+	}
+}
+
+func (s *sanitizer) safeURL(url string) bool {
+	// browsers ignore whitespace and control characters in urls, so "java\tscript:" is still javascript
+	cleaned := []byte{}
+	for i := 0; i < len(url); i++ {
+		if url[i] > ' ' {
+			cleaned = append(cleaned, url[i])
+		}
+	}
+
+	for i, c := range cleaned {
+		switch c {
+		case '/', '?', '#':
+			// no scheme, so relative to the page
+			return true
+		case ':':
+			return contains(s.policy.URLSchemes, toLower(string(cleaned[:i])))
+		}
+	}
+	return true
+}
+
+// decodeEntities handles the character references that could be used to hide a url scheme, anything else is left
+// alone and ends up escaped in the output, so the browser won't decode it either
+func decodeEntities(s string) string {
+	out := []byte{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' {
+			out = append(out, s[i])
+			continue
+		}
+
+		if r, n := numericEntity(s[i:]); n > 0 {
+			out = append(out, string(r)...)
+			i += n - 1
+			continue
+		}
+
+		named := false
+		for name, value := range namedEntities {
+			if hasPrefix(s[i+1:], name) {
+				out = append(out, value...)
+				i += len(name)
+				named = true
+				break
+			}
+		}
+		if !named {
+			out = append(out, '&')
+		}
+	}
+	return string(out)
+}
+
+// numericEntity decodes &#NN; or &#xHH; at the start of s, browsers accept these without the semicolon
+func numericEntity(s string) (rune, int) {
+	if len(s) < 3 || s[1] != '#' {
+		return 0, 0
+	}
+
+	i := 2
+	base := rune(10)
+	if s[i] == 'x' || s[i] == 'X' {
+		base = 16
+		i++
+	}
+
+	start := i
+	r := rune(0)
+	for ; i < len(s); i++ {
+		var d rune
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			d = rune(c - '0')
+		case base == 16 && c >= 'a' && c <= 'f':
+			d = rune(c-'a') + 10
+		case base == 16 && c >= 'A' && c <= 'F':
+			d = rune(c-'A') + 10
+		default:
+			d = -1
+		}
+		if d < 0 {
+			break
+		}
+		if r < 0x110000 {
+			r = r*base + d
+		}
+	}
+	if i == start {
+		return 0, 0
+	}
+	if i < len(s) && s[i] == ';' {
+		i++
+	}
+	if r == 0 || r >= 0x110000 || (r >= 0xd800 && r < 0xe000) {
+		r = 0xfffd
+	}
+	return r, i
+}
+
+func escapeAttribute(s string) string {
+	out := []byte{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			out = append(out, "&amp;"...)
+		case '"':
+			out = append(out, "&quot;"...)
+		case '<':
+			out = append(out, "&lt;"...)
+		case '>':
+			out = append(out, "&gt;"...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlphanumeric(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func toLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func indexOf(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
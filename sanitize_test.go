@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want string
+	}{
+		// allowed markup is kept
+		{`<p>hi <b>there</b></p>`, `<p>hi <b>there</b></p>`},
+		{`<img src=x alt="a" width=10 height="20">`, `<img src="x" alt="a" width="10" height="20">`},
+		{`<td colspan=2>c</td>`, `<td colspan="2">c</td>`},
+		{`<p title='a"b'>x</p>`, `<p title="a&quot;b">x</p>`},
+		{`<p>1 &lt; 2 &amp; 3 > 2</p>`, `<p>1 &lt; 2 &amp; 3 &gt; 2</p>`},
+		{`<p>unclosed`, `<p>unclosed</p>`},
+		{`</p>stray`, `stray`},
+
+		// unknown tags lose their markup but not their text
+		{`<unknown>text</unknown>`, `text`},
+
+		// script, style and the like go with everything in them
+		{`<script>alert(1)</script>ok`, `ok`},
+		{`<SCRIPT src=x></SCRIPT>ok`, `ok`},
+		{`<style>p{color:red}</style>ok`, `ok`},
+		{`<svg><script>alert(1)</script></svg>ok`, `ok`},
+		{`<iframe src="https://example.com"></iframe>ok`, `ok`},
+		{`<textarea><script>alert(1)</script></textarea>ok`, `ok`},
+		{`<!-- <script>alert(1)</script> -->ok`, `ok`},
+
+		// event handlers and attributes that aren't allowed
+		{`<p onclick="alert(1)" title="t">x</p>`, `<p title="t">x</p>`},
+		{`<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{`<td colspan=2 onmouseover=x>c</td>`, `<td colspan="2">c</td>`},
+		{`<p style="color:red" class="c">x</p>`, `<p>x</p>`},
+
+		// urls with schemes that aren't allowed, however they are spelled
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{`<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{`<img src="data:image/png;base64,AAAA">`, `<img>`},
+		{`<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a>x</a>`},
+
+		// entity encoded schemes
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="&#106;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="&#x6A;avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="&#0000106avascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="javascript&colon;alert(1)">x</a>`, `<a>x</a>`},
+
+		// urls that are fine
+		{`<a href="https://example.com/?a=1&amp;b=2">x</a>`, `<a href="https://example.com/?a=1&amp;b=2">x</a>`},
+		{`<a href="mailto:a@example.com">x</a>`, `<a href="mailto:a@example.com">x</a>`},
+		{`<a href="/relative/path">x</a>`, `<a href="/relative/path">x</a>`},
+		{`<a href="#top">x</a>`, `<a href="#top">x</a>`},
+		{`<a href="foo/bar:baz">x</a>`, `<a href="foo/bar:baz">x</a>`},
+	} {
+		if got := SanitizeHTML(c.in, DefaultPolicy); got != c.want {
+			t.Errorf("%s\ngot  %s\nwant %s", c.in, got, c.want)
+		}
+	}
+}
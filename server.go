@@ -0,0 +1,216 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server mode runs render() natively, one session per websocket connection, and streams patches to a
+// page loaded with ?remote. ui.go's state and the app's view state are swapped in for each session, but
+// app state such as todos is shared, so every connected page shows the same list.
+
+var (
+	sessionLock sync.Mutex
+	sessions    = map[*Session]bool{}
+	serverStart = time.Now()
+
+	// the session whose ui state is loaded, see run
+	currentSession *Session
+)
+
+type Session struct {
+	state   uiState
+	backend *sessionBackend
+}
+
+// sessionBackend notes when the app asks for a frame, see Receive
+type sessionBackend struct {
+	*messageBackend
+	requested bool
+}
+
+func (b *sessionBackend) RequestFrame() {
+	b.requested = true
+	b.messageBackend.RequestFrame()
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	assets := flag.String("assets", ".", "directory with index.html and the gopherjs build of main.js")
+	flag.Parse()
+
+	http.Handle("/ws", RemoteHandler())
+	http.Handle("/", AssetHandler(*assets))
+
+	log.Printf("serving on http://%s/?remote", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func serverNow() float64 {
+	return float64(time.Since(serverStart)) / float64(time.Millisecond)
+}
+
+// NewSession starts rendering for a new host on the other side of send, which must not block
+func NewSession(send func(msg string)) *Session {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	s := &Session{backend: &sessionBackend{messageBackend: newMessageBackend(send, serverNow)}}
+	s.state = newUIState(s.backend)
+	sessions[s] = true
+
+	s.run(Rerender)
+	return s
+}
+
+func (s *Session) Receive(msg string) {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	rendered := false
+	s.backend.requested = false
+	s.run(func() {
+		rendered = s.backend.Receive(msg)
+	})
+
+	// the app calls Rerender after changing its state, so when a frame asks for another one the shared
+	// state may have changed and everyone else should render again. Input always asks for a frame but
+	// only changes this session's ui state, so it doesn't count.
+	if rendered && s.backend.requested {
+		for other := range sessions {
+			if other != s {
+				other.run(Rerender)
+			}
+		}
+	}
+}
+
+func (s *Session) Close() {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	delete(sessions, s)
+}
+
+// run calls f with this session's ui state loaded, sessionLock must be held
+func (s *Session) run(f func()) {
+	saved, savedSession := saveUIState(), currentSession
+	loadUIState(s.state)
+	currentSession = s
+	defer func() {
+		s.state = saveUIState()
+		loadUIState(saved)
+		currentSession = savedSession
+	}()
+
+	f()
+}
+
+// inSession returns f wrapped to be called later from another goroutine, with sessionLock held and in the session
+// that is running now, if it is still open by then
+func inSession(f func()) func() {
+	s := currentSession
+	return func() {
+		sessionLock.Lock()
+		defer sessionLock.Unlock()
+
+		switch {
+		case s == nil:
+			f()
+		case sessions[s]:
+			s.run(f)
+		}
+	}
+}
+
+// assets are the only files the page needs, anything else in the directory, like the source, isn't served
+var assets = map[string]string{
+	"/":            "index.html",
+	"/index.html":  "index.html",
+	"/main.js":     "main.js",
+	"/main.js.map": "main.js.map",
+}
+
+func AssetHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(dir, name))
+	})
+}
+
+// sameOrigin reports whether a websocket request comes from a page this server served. Browsers always send Origin
+// with websockets, so that other sites can't drive a session from their pages, other clients like RemoteClient don't.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func RemoteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			http.Error(w, "websocket from another origin", http.StatusForbidden)
+			log.Print("refused websocket from origin ", r.Header.Get("Origin"))
+			return
+		}
+
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		defer conn.Close()
+
+		// sessions send while holding sessionLock, so never block there, a client that falls this far behind is dropped
+		out := make(chan string, 256)
+		done := make(chan bool)
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case msg := <-out:
+					if err := conn.WriteMessage(msg); err != nil {
+						conn.Close()
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		s := NewSession(func(msg string) {
+			select {
+			case out <- msg:
+			default:
+				log.Print("remote client is too slow, disconnecting")
+				conn.Close()
+			}
+		})
+		defer s.Close()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			s.Receive(msg)
+		}
+	})
+}
@@ -0,0 +1,340 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRemote connects two clients to the same server, what one adds the other sees
+func TestRemote(t *testing.T) {
+	savedStore := store
+	store = newStore(nil)
+	t.Cleanup(func() {
+		store = savedStore
+	})
+
+	server := httptest.NewServer(RemoteHandler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	dial := func() *RemoteClient {
+		c, err := DialRemote(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		if err := c.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a, b := dial(), dial()
+	if !strings.Contains(EncodeVNode(a.Root), "hello1") {
+		t.Fatal("the first frame doesn't show the todos")
+	}
+
+	a.Values["new-todo"] = "from a"
+	if err := a.Input(InputEvent{Type: eventKeyup, Id: "new-todo", Code: keyEnter}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(EncodeVNode(a.Root), "from a") {
+		t.Fatal("a doesn't show the todo it added")
+	}
+	if a.Values["new-todo"] != "" {
+		t.Fatalf("the new todo input wasn't cleared, it has %q", a.Values["new-todo"])
+	}
+
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(EncodeVNode(b.Root), "from a") {
+		t.Fatal("b doesn't show the todo a added")
+	}
+
+	// more than fits in one websocket frame header's short lengths
+	long := strings.Repeat("x", 70000)
+	a.Values["new-todo"] = long
+	a.Input(InputEvent{Type: eventKeyup, Id: "new-todo", Code: keyEnter})
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(EncodeVNode(a.Root), long) {
+		t.Fatal("a doesn't show the long todo")
+	}
+}
+
+func TestRemoteOrigin(t *testing.T) {
+	for _, c := range []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{"http://example.com:8080", true},
+		{"https://EXAMPLE.com:8080", true},
+		{"http://example.com", false},
+		{"http://evil.com:8080", false},
+		{"null", false},
+	} {
+		r := httptest.NewRequest("GET", "http://example.com:8080/ws", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if sameOrigin(r) != c.ok {
+			t.Errorf("origin %q allowed %v, want %v", c.origin, !c.ok, c.ok)
+		}
+	}
+
+	// refused before upgrading
+	r := httptest.NewRequest("GET", "http://example.com:8080/ws", nil)
+	r.Header.Set("Origin", "http://evil.com")
+	w := httptest.NewRecorder()
+	RemoteHandler().ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d", w.Code)
+	}
+}
+
+func TestAssets(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "main.js", "server.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for path, want := range map[string]string{
+		"/":            "index.html",
+		"/main.js":     "main.js",
+		"/server.go":   "",
+		"/../main.js":  "",
+		"/main.js.map": "",
+	} {
+		w := httptest.NewRecorder()
+		AssetHandler(dir).ServeHTTP(w, httptest.NewRequest("GET", "http://example.com"+path, nil))
+		if want == "" {
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s: got status %d", path, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%s: got status %d and %q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestRemoteConcurrent has clients decode their patches while the server renders for others, go test -race checks
+// that they don't share the vnode pool
+func TestRemoteConcurrent(t *testing.T) {
+	savedStore := store
+	store = newStore(nil)
+	t.Cleanup(func() {
+		store = savedStore
+	})
+
+	server := httptest.NewServer(RemoteHandler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func(name string) {
+			c, err := DialRemote(url)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer c.Close()
+			for j := 0; j < 10; j++ {
+				c.Values["new-todo"] = name
+				if err := c.Input(InputEvent{Type: eventKeyup, Id: "new-todo", Code: keyEnter}); err != nil {
+					errs <- err
+					return
+				}
+				if err := c.Sync(); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}("client " + itoa(i))
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRemoteViewState checks that what one user opens, like the help, stays in their page
+func TestRemoteViewState(t *testing.T) {
+	savedStore := store
+	store = newStore(nil)
+	t.Cleanup(func() {
+		store = savedStore
+	})
+
+	server := httptest.NewServer(RemoteHandler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	dial := func() *RemoteClient {
+		c, err := DialRemote(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		if err := c.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a, b := dial(), dial()
+
+	if err := a.Input(InputEvent{Type: eventShortcut, Id: "?"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(EncodeVNode(a.Root), `"help-close"`) {
+		t.Fatal("the help didn't open for a")
+	}
+
+	// anything that makes b render again
+	if err := b.Input(InputEvent{Type: eventHover, Ids: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(EncodeVNode(b.Root), `"help-close"`) {
+		t.Fatal("the help a opened is open for b too")
+	}
+}
+
+// TestRemoteEditing checks that a user's edit survives another user's frame, which doesn't have the edit focused
+func TestRemoteEditing(t *testing.T) {
+	savedStore := store
+	store = newStore(nil)
+	t.Cleanup(func() {
+		store = savedStore
+	})
+
+	server := httptest.NewServer(RemoteHandler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	dial := func() *RemoteClient {
+		c, err := DialRemote(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		if err := c.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	input := func(c *RemoteClient, ev InputEvent) {
+		if err := c.Input(ev); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Sync(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := dial(), dial()
+
+	input(a, InputEvent{Type: eventDoubleClick, Id: "text-todo-item-1"})
+	if a.Focus != "edit-todo-item-1" {
+		t.Fatalf("a is focused on %q instead of the edit", a.Focus)
+	}
+
+	input(b, InputEvent{Type: eventHover, Ids: []string{}})
+	if strings.Contains(EncodeVNode(b.Root), `"edit-todo-item-1"`) {
+		t.Fatal("b is editing the todo a is editing")
+	}
+
+	input(a, InputEvent{Type: eventHover, Ids: []string{}})
+	if !strings.Contains(EncodeVNode(a.Root), `"edit-todo-item-1"`) {
+		t.Fatal("a stopped editing when b rendered")
+	}
+}
+
+// TestRemoteTimer has a timer set while rendering for one session call back into that session
+func TestRemoteTimer(t *testing.T) {
+	savedStore := store
+	store = newStore(nil)
+	t.Cleanup(func() {
+		store = savedStore
+	})
+
+	server := httptest.NewServer(RemoteHandler())
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	sessionLock.Lock()
+	before := map[*Session]bool{}
+	for s := range sessions {
+		before[s] = true
+	}
+	sessionLock.Unlock()
+
+	dial := func() *RemoteClient {
+		c, err := DialRemote(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		if err := c.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a := dial()
+
+	sessionLock.Lock()
+	var session *Session
+	for s := range sessions {
+		if !before[s] {
+			session = s
+		}
+	}
+	session.run(func() {
+		serverTimers{}.AfterFunc(time.Millisecond, func() {
+			showHelp = true
+			Rerender()
+		})
+	})
+	sessionLock.Unlock()
+	b := dial()
+
+	if err := a.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(EncodeVNode(a.Root), `"help-close"`) {
+		t.Fatal("the timer didn't call back into a's session")
+	}
+
+	if err := b.Input(InputEvent{Type: eventHover, Ids: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(EncodeVNode(b.Root), `"help-close"`) {
+		t.Fatal("the timer called back into b's session")
+	}
+}
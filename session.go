@@ -0,0 +1,159 @@
+package main
+
+// uiState is everything ui.go keeps between frames for one user, along with the app's view state, the server swaps it
+// in and out around each message
+type uiState struct {
+	clickable       map[string]bool
+	doubleClickable map[string]bool
+	hoverable       map[string]bool
+	keyupable       map[string]bool
+	keyupableCodes  map[string][]int
+	scrollable      map[string]bool
+	linkable        map[string]bool
+	shortcuts       map[string]bool
+	uploadable      map[string]bool
+
+	inputValues map[string]string
+
+	clickId        string
+	doubleClickId  string
+	focusId        string
+	focusSelection [2]int
+	keyupId        string
+	keyupCode      int
+	shortcutKey    string
+	uploadId       string
+	upload         File
+	downloads      []File
+	notifications  []Notification
+	askNotify      bool
+	revealIds      []string
+	hoverIds       []string
+	scrollTops     map[string]float64
+	location       string
+	focusTrap      string
+	activeTrap     string
+	trapReturnId   string
+	measured       map[string]bool
+	rects          map[string]Rect
+	rowHeights     map[string]float64
+
+	previousRoot *VNode
+	commits      int
+	backend      Backend
+	recording    *Recording
+	debugOverlay bool
+	lastPatches  []Patch
+
+	// see viewState
+	view viewState
+}
+
+func newUIState(b Backend) uiState {
+	return uiState{
+		clickable:       map[string]bool{},
+		doubleClickable: map[string]bool{},
+		hoverable:       map[string]bool{},
+		keyupable:       map[string]bool{},
+		keyupableCodes:  map[string][]int{},
+		scrollable:      map[string]bool{},
+		linkable:        map[string]bool{},
+		shortcuts:       map[string]bool{},
+		uploadable:      map[string]bool{},
+		inputValues:     map[string]string{},
+		focusSelection:  [2]int{-1, -1},
+		hoverIds:        []string{},
+		scrollTops:      map[string]float64{},
+		measured:        map[string]bool{},
+		rects:           map[string]Rect{},
+		rowHeights:      map[string]float64{},
+		backend:         b,
+		view:            newViewState(),
+	}
+}
+
+func saveUIState() uiState {
+	return uiState{
+		clickable:       clickable,
+		doubleClickable: doubleClickable,
+		hoverable:       hoverable,
+		keyupable:       keyupable,
+		keyupableCodes:  keyupableCodes,
+		scrollable:      scrollable,
+		linkable:        linkable,
+		shortcuts:       shortcuts,
+		uploadable:      uploadable,
+		inputValues:     InputValues,
+		clickId:         clickId,
+		doubleClickId:   doubleClickId,
+		focusId:         focusId,
+		focusSelection:  focusSelection,
+		keyupId:         keyupId,
+		keyupCode:       keyupCode,
+		shortcutKey:     shortcutKey,
+		uploadId:        uploadId,
+		upload:          upload,
+		downloads:       downloads,
+		notifications:   notifications,
+		askNotify:       askNotify,
+		revealIds:       revealIds,
+		hoverIds:        hoverIds,
+		scrollTops:      scrollTops,
+		location:        location,
+		focusTrap:       focusTrap,
+		activeTrap:      activeTrap,
+		trapReturnId:    trapReturnId,
+		measured:        measured,
+		rects:           rects,
+		rowHeights:      virtualRowHeights,
+		previousRoot:    PreviousRoot,
+		commits:         commits,
+		backend:         backend,
+		recording:       recording,
+		debugOverlay:    DebugOverlay,
+		lastPatches:     lastPatches,
+		view:            saveViewState(),
+	}
+}
+
+func loadUIState(s uiState) {
+	clickable = s.clickable
+	doubleClickable = s.doubleClickable
+	hoverable = s.hoverable
+	keyupable = s.keyupable
+	keyupableCodes = s.keyupableCodes
+	scrollable = s.scrollable
+	linkable = s.linkable
+	shortcuts = s.shortcuts
+	uploadable = s.uploadable
+	InputValues = s.inputValues
+	clickId = s.clickId
+	doubleClickId = s.doubleClickId
+	focusId = s.focusId
+	focusSelection = s.focusSelection
+	keyupId = s.keyupId
+	keyupCode = s.keyupCode
+	shortcutKey = s.shortcutKey
+	uploadId = s.uploadId
+	upload = s.upload
+	downloads = s.downloads
+	notifications = s.notifications
+	askNotify = s.askNotify
+	revealIds = s.revealIds
+	hoverIds = s.hoverIds
+	scrollTops = s.scrollTops
+	location = s.location
+	focusTrap = s.focusTrap
+	activeTrap = s.activeTrap
+	trapReturnId = s.trapReturnId
+	measured = s.measured
+	rects = s.rects
+	virtualRowHeights = s.rowHeights
+	PreviousRoot = s.previousRoot
+	commits = s.commits
+	backend = s.backend
+	recording = s.recording
+	DebugOverlay = s.debugOverlay
+	lastPatches = s.lastPatches
+	loadViewState(s.view)
+}
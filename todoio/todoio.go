@@ -0,0 +1,516 @@
+// Package todoio reads and writes todos as JSON, CSV and todo.txt, and merges imported todos into an existing list.
+// It only uses duedate, minjson and small standard packages, so that it can be tested without a browser and doesn't
+// pull encoding/json into the gopherjs bundle. Due dates are read in the local time zone.
+package todoio
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+	"github.com/christopherhesse/todomvc-gopherjs-im/minjson"
+)
+
+type Todo struct {
+	Id        int
+	Text      string
+	Completed bool
+	Due       duedate.Due
+}
+
+type Format int
+
+const (
+	// [{"id":0,"text":"hello","completed":true,"due":"2006-01-02"}]
+	JSON Format = iota
+	// id,text,completed,due with a header row
+	CSV
+	// one todo per line, completed ones start with "x " and due ones end with a due:2006-01-02 tag, see
+	// https://github.com/todotxt/todo.txt
+	TodoTxt
+)
+
+var Formats = []Format{JSON, CSV, TodoTxt}
+
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "JSON"
+	case CSV:
+		return "CSV"
+	case TodoTxt:
+		return "todo.txt"
+	}
+	return "Format(" + strconv.Itoa(int(f)) + ")"
+}
+
+func (f Format) Extension() string {
+	switch f {
+	case JSON:
+		return ".json"
+	case CSV:
+		return ".csv"
+	}
+	return ".txt"
+}
+
+func (f Format) MediaType() string {
+	switch f {
+	case JSON:
+		return "application/json"
+	case CSV:
+		return "text/csv"
+	}
+	return "text/plain"
+}
+
+// FormatOf picks a format from a file name's extension
+func FormatOf(name string) (Format, bool) {
+	for _, f := range Formats {
+		if strings.HasSuffix(strings.ToLower(name), f.Extension()) {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// Error is returned for input that can't be imported, Line is 1-based and 0 when the problem isn't with one line.
+// For JSON, Line is the index of the todo in the array plus one.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+func Export(todos []Todo, f Format) string {
+	switch f {
+	case JSON:
+		// a todo per line
+		w := &minjson.Writer{}
+		w.Raw("[")
+		for i, todo := range todos {
+			if i > 0 {
+				w.Raw(",")
+			}
+			w.Raw("\n  {\"id\":")
+			w.Int(todo.Id)
+			w.Raw(`,"text":`)
+			w.String(todo.Text)
+			w.Raw(`,"completed":`)
+			w.Bool(todo.Completed)
+			if !todo.Due.IsZero() {
+				w.Raw(`,"due":`)
+				w.String(todo.Due.String())
+			}
+			w.Raw("}")
+		}
+		if len(todos) > 0 {
+			w.Raw("\n")
+		}
+		w.Raw("]\n")
+		return w.Done()
+	case CSV:
+		buf := &bytes.Buffer{}
+		writeCSV(buf, []string{"id", "text", "completed", "due"})
+		for _, todo := range todos {
+			writeCSV(buf, []string{strconv.Itoa(todo.Id), todo.Text, strconv.FormatBool(todo.Completed), todo.Due.String()})
+		}
+		return buf.String()
+	case TodoTxt:
+		buf := &bytes.Buffer{}
+		for _, todo := range todos {
+			if todo.Completed {
+				buf.WriteString("x ")
+			}
+			// a todo is a line
+			buf.WriteString(strings.Join(strings.Fields(todo.Text), " "))
+			if !todo.Due.IsZero() {
+				buf.WriteString(" " + dueTag + todo.Due.String())
+			}
+			buf.WriteString("\n")
+		}
+		return buf.String()
+	}
+	panic("todoio: unknown format " + f.String())
+}
+
+// Parse reads todos written in format f. Every todo needs some text, ids are read where the format has them but
+// Import assigns new ones anyway.
+func Parse(data string, f Format) ([]Todo, error) {
+	var todos []Todo
+	var err error
+	switch f {
+	case JSON:
+		todos, err = parseJSON(data)
+	case CSV:
+		todos, err = parseCSV(data)
+	case TodoTxt:
+		todos, err = parseTodoTxt(data)
+	default:
+		panic("todoio: unknown format " + f.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(todos) == 0 {
+		return nil, &Error{Msg: "no todos found"}
+	}
+	return todos, nil
+}
+
+func parseJSON(data string) ([]Todo, error) {
+	v, err := minjson.Parse(data)
+	if err != nil {
+		return nil, &Error{Msg: "not a JSON list of todos: " + err.Error()}
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, &Error{Msg: "not a JSON list of todos"}
+	}
+
+	todos := []Todo{}
+	for i, e := range list {
+		line := i + 1
+		t, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, &Error{Line: line, Msg: "todo is not an object"}
+		}
+
+		s, ok := t["text"].(string)
+		if !ok {
+			return nil, &Error{Line: line, Msg: "todo has no text"}
+		}
+		text, err := cleanText(s, line)
+		if err != nil {
+			return nil, err
+		}
+		todo := Todo{Text: text}
+
+		if id, ok := t["id"]; ok {
+			f, ok := id.(float64)
+			if !ok || f != float64(int(f)) {
+				return nil, &Error{Line: line, Msg: "id is not a number"}
+			}
+			todo.Id = int(f)
+		}
+		if completed, ok := t["completed"]; ok {
+			if todo.Completed, ok = completed.(bool); !ok {
+				return nil, &Error{Line: line, Msg: "completed is not true or false"}
+			}
+		}
+		if due, ok := t["due"]; ok {
+			s, ok := due.(string)
+			if !ok {
+				return nil, &Error{Line: line, Msg: "due date is not a string"}
+			}
+			if todo.Due, err = parseDue(s, line); err != nil {
+				return nil, err
+			}
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+// the header decides the order of the columns, only text is required
+func parseCSV(data string) ([]Todo, error) {
+	records, lines, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, &Error{Msg: "no header row"}
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	textColumn, ok := columns["text"]
+	if !ok {
+		return nil, &Error{Line: 1, Msg: "no text column"}
+	}
+
+	todos := []Todo{}
+	for i, record := range records[1:] {
+		line := lines[i+1]
+		if len(record) != len(records[0]) {
+			return nil, &Error{Line: line, Msg: "has " + strconv.Itoa(len(record)) + " fields, the header has " + strconv.Itoa(len(records[0]))}
+		}
+		text, err := cleanText(record[textColumn], line)
+		if err != nil {
+			return nil, err
+		}
+		todo := Todo{Text: text}
+
+		if c, ok := columns["id"]; ok && record[c] != "" {
+			if todo.Id, err = strconv.Atoi(record[c]); err != nil {
+				return nil, &Error{Line: line, Msg: "id is not a number: " + strconv.Quote(record[c])}
+			}
+		}
+		if c, ok := columns["completed"]; ok {
+			switch strings.ToLower(strings.TrimSpace(record[c])) {
+			case "true", "yes", "x", "1":
+				todo.Completed = true
+			case "false", "no", "", "0":
+			default:
+				return nil, &Error{Line: line, Msg: "completed is not true or false: " + strconv.Quote(record[c])}
+			}
+		}
+		if c, ok := columns["due"]; ok {
+			if todo.Due, err = parseDue(strings.TrimSpace(record[c]), line); err != nil {
+				return nil, err
+			}
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+// writeCSV writes a record, quoting fields that need it
+func writeCSV(buf *bytes.Buffer, record []string) {
+	for i, field := range record {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if field == "" || !strings.ContainsAny(field, ",\"\r\n") && field[0] != ' ' && field[0] != '\t' {
+			buf.WriteString(field)
+			continue
+		}
+		buf.WriteByte('"')
+		buf.WriteString(strings.Replace(field, `"`, `""`, -1))
+		buf.WriteByte('"')
+	}
+	buf.WriteString("\n")
+}
+
+// readCSV reads RFC 4180 CSV and returns the records and the line each of them starts on. Blank lines are skipped.
+func readCSV(data string) ([][]string, []int, error) {
+	records := [][]string{}
+	lines := []int{}
+	line := 1
+
+	record := []string{}
+	field := []byte{}
+	// the line the record starts on, and whether it has anything in it yet
+	start := line
+	empty := true
+	inQuotes := false
+
+	endRecord := func() {
+		if !empty {
+			records = append(records, append(record, string(field)))
+			lines = append(lines, start)
+		}
+		record, field, empty = []string{}, []byte{}, true
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if empty && c != '\r' && c != '\n' {
+			empty = false
+			start = line
+		}
+
+		if inQuotes {
+			switch {
+			case c == '"' && i+1 < len(data) && data[i+1] == '"':
+				field = append(field, '"')
+				i++
+			case c == '"':
+				inQuotes = false
+				if i+1 < len(data) && data[i+1] != ',' && data[i+1] != '\r' && data[i+1] != '\n' {
+					return nil, nil, &Error{Line: line, Msg: `extraneous " in quoted field`}
+				}
+			default:
+				if c == '\n' {
+					line++
+				}
+				field = append(field, c)
+			}
+			continue
+		}
+
+		switch c {
+		case ',':
+			record = append(record, string(field))
+			field = []byte{}
+		case '"':
+			if len(field) != 0 {
+				return nil, nil, &Error{Line: line, Msg: `bare " in field`}
+			}
+			inQuotes = true
+		case '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				continue
+			}
+			field = append(field, c)
+		case '\n':
+			endRecord()
+			line++
+		default:
+			field = append(field, c)
+		}
+	}
+	if inQuotes {
+		return nil, nil, &Error{Line: start, Msg: `quoted field doesn't end`}
+	}
+	endRecord()
+	return records, lines, nil
+}
+
+// priorities, projects and contexts are left in the text, dates after the completion marker are dropped since
+// todos don't have them. A due: tag anywhere in the line is the due date, due: words that aren't dates are left in the
+// text since todos like "ask about due:date" are written without escaping.
+func parseTodoTxt(data string) ([]Todo, error) {
+	todos := []Todo{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		todo := Todo{}
+		if strings.HasPrefix(line, "x ") {
+			todo.Completed = true
+			line = line[2:]
+			// completion date, then creation date
+			for j := 0; j < 2; j++ {
+				if fields := strings.SplitN(strings.TrimLeft(line, " "), " ", 2); len(fields) == 2 && isDate(fields[0]) {
+					line = fields[1]
+				}
+			}
+		} else if fields := strings.SplitN(line, " ", 2); len(fields) == 2 && isDate(fields[0]) {
+			line = fields[1]
+		}
+
+		words := []string{}
+		for _, word := range strings.Split(line, " ") {
+			if strings.HasPrefix(word, dueTag) && word != dueTag {
+				if due, err := parseDue(word[len(dueTag):], i+1); err == nil {
+					todo.Due = due
+					continue
+				}
+			}
+			words = append(words, word)
+		}
+
+		text, err := cleanText(strings.Join(words, " "), i+1)
+		if err != nil {
+			return nil, err
+		}
+		todo.Text = text
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+const dueTag = "due:"
+
+// parseDue reads a due date written by Due.String, an empty one is no due date
+func parseDue(s string, line int) (duedate.Due, error) {
+	if s == "" {
+		return duedate.Due{}, nil
+	}
+	due, err := duedate.ParseString(s, time.Local)
+	if err != nil {
+		return duedate.Due{}, &Error{Line: line, Msg: "due date is not 2006-01-02 or 2006-01-02T15:04: " + strconv.Quote(s)}
+	}
+	return due, nil
+}
+
+// isDate reports whether s looks like 2006-01-02
+func isDate(s string) bool {
+	if len(s) != 10 || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+	for i, c := range s {
+		if i != 4 && i != 7 && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func cleanText(text string, line int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", &Error{Line: line, Msg: "todo has no text"}
+	}
+	return text, nil
+}
+
+type Mode int
+
+const (
+	// Merge keeps the existing todos, an imported todo with the same text as one of them only updates whether it is
+	// completed and its due date, if it has one
+	Merge Mode = iota
+	// Replace throws the existing todos away
+	Replace
+)
+
+type Result struct {
+	Todos []Todo
+	// the id after the last one handed out
+	NextId int
+
+	Added   int
+	Updated int
+}
+
+// Import combines existing and imported todos. Imported todos always get new ids counting up from nextId, so that
+// they can't collide with existing todos or with todos that were deleted but might come back with an undo.
+// existing isn't modified.
+func Import(existing []Todo, imported []Todo, nextId int, mode Mode) Result {
+	result := Result{Todos: []Todo{}, NextId: nextId}
+	if mode == Merge {
+		result.Todos = append(result.Todos, existing...)
+	}
+
+	for _, todo := range imported {
+		i := -1
+		if mode == Merge {
+			i = indexOfText(result.Todos, todo.Text)
+		}
+
+		if i == -1 {
+			todo.Id = result.NextId
+			result.NextId++
+			result.Todos = append(result.Todos, todo)
+			result.Added++
+			continue
+		}
+
+		updated := false
+		if result.Todos[i].Completed != todo.Completed {
+			result.Todos[i].Completed = todo.Completed
+			updated = true
+		}
+		if !todo.Due.IsZero() && !result.Todos[i].Due.Equal(todo.Due) {
+			result.Todos[i].Due = todo.Due
+			updated = true
+		}
+		if updated {
+			result.Updated++
+		}
+	}
+	return result
+}
+
+func indexOfText(todos []Todo, text string) int {
+	for i, todo := range todos {
+		if todo.Text == text {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,174 @@
+package todoio
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+)
+
+func TestRoundTrip(t *testing.T) {
+	todos := []Todo{
+		{Id: 3, Text: `a, "quoted"`, Completed: true},
+		{Id: 7, Text: "b"},
+		{Id: 8, Text: " leading space and unicode é ✓"},
+		{Id: 9, Text: `back\slash`},
+	}
+	for _, f := range Formats {
+		out := Export(todos, f)
+		back, err := Parse(out, f)
+		if err != nil {
+			t.Fatalf("%v: %v\n%s", f, err, out)
+		}
+		if len(back) != len(todos) {
+			t.Fatalf("%v: %+v", f, back)
+		}
+		for i := range todos {
+			// todo.txt has no ids and text is trimmed on the way in
+			if back[i].Text != strings.TrimSpace(todos[i].Text) || back[i].Completed != todos[i].Completed {
+				t.Errorf("%v: todo %d came back as %+v", f, i, back[i])
+			}
+			if f != TodoTxt && back[i].Id != todos[i].Id {
+				t.Errorf("%v: todo %d has id %d", f, i, back[i].Id)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	todos := []Todo{{Id: 0, Text: "a", Completed: true}, {Id: 1, Text: `b, "c"`}}
+	tests := []struct {
+		f   Format
+		out string
+	}{
+		{JSON, "[\n  {\"id\":0,\"text\":\"a\",\"completed\":true},\n  {\"id\":1,\"text\":\"b, \\\"c\\\"\",\"completed\":false}\n]\n"},
+		{CSV, "id,text,completed,due\n0,a,true,\n1,\"b, \"\"c\"\"\",false,\n"},
+		{TodoTxt, "x a\nb, \"c\"\n"},
+	}
+	for _, test := range tests {
+		if out := Export(todos, test.f); out != test.out {
+			t.Errorf("%v: %q, want %q", test.f, out, test.out)
+		}
+	}
+	if out := Export(nil, JSON); out != "[]\n" {
+		t.Errorf("empty: %q", out)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		f    Format
+		err  string
+	}{
+		{`{}`, JSON, "not a JSON list of todos"},
+		{`[{"text":"a"`, JSON, "not a JSON list of todos: json: "},
+		{`[{"text":"a"},{"completed":true}]`, JSON, "line 2: todo has no text"},
+		{`[{"text":"a"},{"text":"  "}]`, JSON, "line 2: todo has no text"},
+		{`["a"]`, JSON, "line 1: todo is not an object"},
+		{`[{"text":"a","completed":"yes"}]`, JSON, "line 1: completed is not true or false"},
+		{`[{"text":"a","id":1.5}]`, JSON, "line 1: id is not a number"},
+		{`[{"text":"a","due":"soon"}]`, JSON, `line 1: due date is not 2006-01-02 or 2006-01-02T15:04: "soon"`},
+		{`[]`, JSON, "no todos found"},
+		{"", CSV, "no header row"},
+		{"id,completed\n1,true\n", CSV, "line 1: no text column"},
+		{"text,completed\na,maybe\n", CSV, `line 2: completed is not true or false: "maybe"`},
+		{"id,text\nx,a\n", CSV, `line 2: id is not a number: "x"`},
+		{"text,due\na,2026-02-30\n", CSV, `line 2: due date is not 2006-01-02 or 2006-01-02T15:04: "2026-02-30"`},
+		{"text\n\"a\nb\",\"\"\nc\n\n\"d\n", CSV, `line 6: quoted field doesn't end`},
+		{"text\na\"b\n", CSV, `line 2: bare " in field`},
+		{"text\n\"a\"b\n", CSV, `line 2: extraneous " in quoted field`},
+		{"text,completed\n\"multi\nline\",true\nb\n", CSV, "line 4: has 1 fields, the header has 2"},
+		{"\n\n", TodoTxt, "no todos found"},
+		{"x \n", TodoTxt, "line 1: todo has no text"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.data, test.f)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Parse(%q, %v) = %v, want %q", test.data, test.f, err, test.err)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	todos, err := Parse("Completed,Text\r\n\r\nyes,\"two\r\nlines\"\r\n,plain\r\n", CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Text != "two\r\nlines" || !todos[0].Completed || todos[1].Text != "plain" || todos[1].Completed {
+		t.Fatalf("%+v", todos)
+	}
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	todos, err := Parse("x 2020-01-01 2019-12-31 done thing\n(A) 2020-01-02 call mom +family\n", TodoTxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todos[0].Text != "done thing" || !todos[0].Completed || todos[1].Text != "(A) 2020-01-02 call mom +family" || todos[1].Completed {
+		t.Fatalf("%+v", todos)
+	}
+}
+
+func TestImport(t *testing.T) {
+	existing := []Todo{{Id: 0, Text: "a"}, {Id: 1, Text: "b", Completed: true}}
+	imported := []Todo{{Id: 0, Text: "a", Completed: true}, {Id: 1, Text: "b", Completed: true}, {Id: 5, Text: "c"}, {Id: 0, Text: "c"}}
+
+	// merging keeps the existing ids and gives new todos fresh ones, whatever ids they came with
+	r := Import(existing, imported, 9, Merge)
+	if r.Added != 1 || r.Updated != 1 || r.NextId != 10 || len(r.Todos) != 3 {
+		t.Fatalf("%+v", r)
+	}
+	if r.Todos[0].Id != 0 || !r.Todos[0].Completed || r.Todos[1].Id != 1 || r.Todos[2].Id != 9 || r.Todos[2].Text != "c" {
+		t.Fatalf("%+v", r.Todos)
+	}
+	if existing[0].Completed {
+		t.Fatal("existing todos were modified")
+	}
+
+	// replacing throws the existing todos away, every imported one gets a new id, even the ones that collide
+	r = Import(existing, imported, 9, Replace)
+	if r.Added != 4 || r.Updated != 0 || r.NextId != 13 || len(r.Todos) != 4 {
+		t.Fatalf("%+v", r)
+	}
+	for i, todo := range r.Todos {
+		if todo.Id != 9+i || todo.Text != imported[i].Text {
+			t.Fatalf("%+v", r.Todos)
+		}
+	}
+}
+
+func TestDueRoundTrip(t *testing.T) {
+	due := duedate.Due{At: time.Date(2026, 10, 21, 17, 30, 0, 0, time.Local), HasTime: true}
+	todos := []Todo{
+		{Id: 0, Text: "rent", Due: due},
+		{Id: 1, Text: "b", Completed: true, Due: duedate.Day(due.At)},
+		{Id: 2, Text: "ask about due:date"},
+		{Id: 3, Text: "due: soon", Due: due},
+	}
+	for _, f := range Formats {
+		parsed, err := Parse(Export(todos, f), f)
+		if err != nil {
+			t.Fatal(f, err)
+		}
+		for i := range todos {
+			if parsed[i].Text != todos[i].Text || !parsed[i].Due.Equal(todos[i].Due) {
+				t.Errorf("%v: todo %d came back as %+v", f, i, parsed[i])
+			}
+		}
+	}
+}
+
+func TestTodoTxtDue(t *testing.T) {
+	todos, err := Parse("call mom due:2026-10-21 +family\nx due:later\n", TodoTxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todos[0].Text != "call mom +family" || todos[0].Due.String() != "2026-10-21" {
+		t.Errorf("%+v", todos[0])
+	}
+	if todos[1].Text != "due:later" || !todos[1].Due.IsZero() || !todos[1].Completed {
+		t.Errorf("%+v", todos[1])
+	}
+}
@@ -3,55 +3,76 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"time"
 
-	"github.com/gopherjs/gopherjs/js"
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
 )
 
 type Todo struct {
 	Id        int
 	Text      string
 	Completed bool
+	Due       duedate.Due
 }
 
 var (
-	PreviousRoot *VNode
-	todos        = []Todo{{
-		Id:        0,
-		Text:      "hello0",
-		Completed: true,
-	}, {
-		Id:        1,
-		Text:      "hello1",
-		Completed: false,
-	}, {
-		Id:        2,
-		Text:      "hello2",
-		Completed: false,
-	}}
-	todoCounter   = 3
-	editingTodoId = -1
+	store = newStore(appStorage())
+
+	// only the view cares about these
+	highlightedTodoId = -1
+	showHelp          = false
+	// the todo being edited, -1 for none
+	editingId = -1
 )
 
 const (
 	svgCheckedCheckbox = `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40" viewBox="-10 -18 100 135"><circle cx="50" cy="50" r="50" fill="none" stroke="#bddad5" stroke-width="3"/><path fill="#5dc2af" d="M72 25L42 71 27 56l-4 4 20 20 34-52z"/></svg>`
 	svgEmptyCheckbox   = `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40" viewBox="-10 -18 100 135"><circle cx="50" cy="50" r="50" fill="none" stroke="#ededed" stroke-width="3"/></svg>`
 
-	keyEnter = 13
-	keyEsc   = 27
-
 	filterAll       = "all"
 	filterActive    = "active"
 	filterCompleted = "completed"
+	// todos that aren't completed and are due today or overdue
+	filterDueToday = "today"
 )
 
-func main() {
-	Setup()
-	Rerender()
+// newStore starts with the saved todos when storage isn't nil, and saves them as they change
+func newStore(storage Storage) *Store {
+	state := AppState{
+		Lists: []TodoList{{Id: 0, Name: "Todos", Todos: []Todo{{
+			Id:        0,
+			Text:      "hello0",
+			Completed: true,
+		}, {
+			Id:        1,
+			Text:      "hello1",
+			Completed: false,
+		}, {
+			Id:        2,
+			Text:      "hello2",
+			Completed: false,
+		}}}},
+		NextId:     3,
+		NextListId: 1,
+	}
+	middleware := []Middleware{logActions, undoHistory}
+	if storage != nil {
+		state = restoreLists(storage, state)
+		middleware = append(middleware, persistLists(storage))
+	}
+
+	s := NewStore(state, reduce, middleware...)
+	s.Subscribe(Rerender)
+	return s
 }
 
 func render() {
 	Init("body")
 
+	HandleShortcuts()
+	HandleUndoShortcuts()
+	HandleReminders()
+
 	Div(func() {
 		Style(
 			"background", "#fff",
@@ -79,7 +100,7 @@ func render() {
 			DrawNewTodo()
 		})
 
-		if len(todos) == 0 {
+		if len(currentList().Todos) == 0 {
 			// shouldn't draw the rest of this stuff in this case
 			return
 		}
@@ -89,6 +110,10 @@ func render() {
 		DrawFooter()
 	})
 
+	DrawLists()
+
+	DrawImportExport()
+
 	Div(func() {
 		Id("info")
 
@@ -100,27 +125,55 @@ func render() {
 			"text-shadow", "0px 1px 0px rgba(255, 255, 255, 0.5)",
 		)
 
-		Raw(`<p>Double-click to edit a todo</p>`)
-		Raw(`<p>Written by Christopher Hesse</p>`)
-		Raw(`<p>Part of <a href="http://todomvc.com">TodoMVC</a></p>`)
+		UnsafeRaw(`<p>Double-click to edit a todo</p>`)
+		UnsafeRaw(`<p>End a todo with a date like "tomorrow" or "friday at 5pm" to make it due</p>`)
+		UnsafeRaw(`<p>Press ? for keyboard shortcuts</p>`)
+		UnsafeRaw(`<p>Written by Christopher Hesse</p>`)
+		UnsafeRaw(`<p>Part of <a href="http://todomvc.com">TodoMVC</a></p>`)
 	})
 
-	root := Done()
-	patches := DiffNodes(PreviousRoot, root)
-	PatchDOM(patches, js.Global.Get("document").Get("body"))
-	PreviousRoot = root
+	DrawToast()
+
+	DrawHelp()
+
+	DrawDebugOverlay()
+
+	Commit(Done())
 }
 
-func getActiveFilter() string {
-	filter := js.Global.Get("window").Get("location").Get("hash").String()
-	if len(filter) <= 2 {
-		return filterAll
+func todoItemId(id int) string {
+	return "todo-item-" + strconv.Itoa(id)
+}
+
+// visibleTodos returns the indexes of the todos that pass the active filter
+func visibleTodos(todos []Todo) []int {
+	activeFilter := getActiveFilter()
+	now := timers.Now()
+	visible := []int{}
+	for i, todo := range todos {
+		if activeFilter == filterCompleted && !todo.Completed {
+			continue
+		}
+		if activeFilter == filterActive && todo.Completed {
+			continue
+		}
+		if activeFilter == filterDueToday && (todo.Completed || !todo.Due.DueBy(now)) {
+			continue
+		}
+		visible = append(visible, i)
 	}
-	return filter[2:]
+	return visible
 }
 
-func setActiveFilter(filter string) {
-	js.Global.Get("history").Call("pushState", nil, "", "#/"+filter)
+func getActiveFilter() string {
+	params, ok := Route("/lists/:id/:filter")
+	if !ok {
+		params, ok = Route("/:filter")
+	}
+	if filter := params["filter"]; ok && (filter == filterActive || filter == filterCompleted || filter == filterDueToday) {
+		return filter
+	}
+	return filterAll
 }
 
 func DrawNewTodo() {
@@ -138,16 +191,20 @@ func DrawNewTodo() {
 			"box-shadow", "inset 0 -2px 1px rgba(0,0,0,0.03)",
 		)
 
-		Attr("placeholder", "What needs to be done?")
+		Attr("placeholder", "What needs to be done?", "aria-label", "New todo")
 
 		if Keyup(newTodo, keyEnter) {
 			value := InputValues[newTodo]
 			if len(value) > 0 {
 				InputValues[newTodo] = ""
 
-				todos = append(todos, Todo{Id: todoCounter, Text: value, Completed: false})
-				todoCounter++
-				Rerender()
+				// "pay rent tomorrow" is due tomorrow
+				text, due := duedate.Parse(value, timers.Now())
+				if !due.IsZero() {
+					// reminders need permission, which can only be asked for now that the user did something
+					AskToNotify()
+				}
+				store.Dispatch(AddTodo{ListId: currentList().Id, Text: text, Due: due})
 			}
 		}
 	})
@@ -161,10 +218,16 @@ func DrawTodos() {
 			"border-top", "1px solid #e6e6e6",
 		)
 
-		Div(func() {
-			toggleAll := "toggle-all"
-			Id(toggleAll)
+		list := currentList()
+		allCompleted := true
+		for _, todo := range list.Todos {
+			if !todo.Completed {
+				allCompleted = false
+				break
+			}
+		}
 
+		toggled := Checkbox("toggle-all", "Mark all as complete", allCompleted, func() {
 			Style(
 				"position", "absolute",
 				"top", "-65px",
@@ -173,27 +236,6 @@ func DrawTodos() {
 				"cursor", "pointer",
 			)
 
-			allCompleted := true
-			for _, todo := range todos {
-				if !todo.Completed {
-					allCompleted = false
-					break
-				}
-			}
-
-			if Clicked(toggleAll) {
-				completed := true
-				if allCompleted {
-					completed = false
-				}
-
-				for i := range todos {
-					todos[i].Completed = completed
-				}
-
-				Rerender()
-			}
-
 			Div(func() {
 				Style(
 					"position", "absolute",
@@ -216,31 +258,33 @@ func DrawTodos() {
 			})
 		})
 
-		Div(func() {
-			activeFilter := getActiveFilter()
-			for i := 0; i < len(todos); i++ {
-				todo := &todos[i]
-				if activeFilter == filterCompleted && !todo.Completed {
-					continue
-				}
-				if activeFilter == filterActive && todo.Completed {
-					continue
-				}
-
-				Div(func() {
-					DrawTodo(todo)
+		if toggled {
+			store.Dispatch(SetAllCompleted{ListId: list.Id, Completed: !allCompleted})
+		}
 
-					if i == len(todos)-1 {
-						Style("border-bottom", "none")
-					}
-				})
+		visible := visibleTodos(list.Todos)
+
+		VirtualList("todo-list", len(visible), VirtualListOptions{
+			Height:             580,
+			EstimatedRowHeight: 58,
+			Overscan:           5,
+			Keep: func(i int) bool {
+				// the edit box closes when it loses focus, which it would if it were scrolled away
+				id := list.Todos[visible[i]].Id
+				return id == editingId || id == highlightedTodoId || id == movingTodoId
+			},
+		}, func(i int) {
+			DrawTodo(list.Todos[visible[i]], editingId)
+
+			if visible[i] == len(list.Todos)-1 {
+				Style("border-bottom", "none")
 			}
 		})
 	})
 }
 
-func DrawTodo(todo *Todo) {
-	item := "todo-item-" + strconv.Itoa(todo.Id)
+func DrawTodo(todo Todo, editingId int) {
+	item := todoItemId(todo.Id)
 	Id(item)
 	editTodo := "edit-" + item
 
@@ -250,14 +294,34 @@ func DrawTodo(todo *Todo) {
 		"border-bottom", "1px solid #ededed",
 	)
 
-	if editingTodoId == todo.Id {
+	if todo.Id == highlightedTodoId {
+		Style(
+			"background", "#fafafa",
+			"box-shadow", "inset 3px 0px 0px #af5b5e",
+		)
+		Attr("aria-current", "true")
+	}
+
+	if editingId == todo.Id {
 		DrawEditingTodo(editTodo, todo)
 	} else {
 		DrawNormalTodo(item, editTodo, todo)
 	}
 }
 
-func DrawEditingTodo(editTodo string, todo *Todo) {
+// editing is view state, with several users on a server each edits their own todo
+func startEditing(id int) {
+	editingId = id
+	Focus("edit-" + todoItemId(id))
+	Rerender()
+}
+
+func stopEditing() {
+	editingId = -1
+	Rerender()
+}
+
+func DrawEditingTodo(editTodo string, todo Todo) {
 	Style(
 		"border-bottom", "none",
 		"padding", "0px",
@@ -280,26 +344,24 @@ func DrawEditingTodo(editTodo string, todo *Todo) {
 			"margin", "0px 0px 0px 43px",
 		)
 
-		Attr("value", todo.Text)
+		Attr("value", todo.Text, "aria-label", "Edit todo")
 
 		if Keyup(editTodo, keyEnter) {
-			todo.Text = InputValues[editTodo]
-			editingTodoId = -1
-			Rerender()
-		}
-
-		if Keyup(editTodo, keyEsc) || !Focused(editTodo) {
-			editingTodoId = -1
-			Rerender()
+			stopEditing()
+			text, due := duedate.Parse(InputValues[editTodo], timers.Now())
+			if !due.IsZero() {
+				AskToNotify()
+			}
+			store.Dispatch(RenameTodo{Id: todo.Id, Text: text, Due: due})
+		} else if Keyup(editTodo, keyEsc) || !Focused(editTodo) {
+			stopEditing()
 		}
 	})
 }
 
-func DrawNormalTodo(item string, editTodo string, todo *Todo) {
-	Div(func() {
-		checkbox := "checkbox-" + item
-		Id(checkbox)
-
+func DrawNormalTodo(item string, editTodo string, todo Todo) {
+	checkbox := "checkbox-" + item
+	toggled := Checkbox(checkbox, todo.Text, todo.Completed, func() {
 		Style(
 			"display", "inline",
 			"text-align", "center",
@@ -312,18 +374,17 @@ func DrawNormalTodo(item string, editTodo string, todo *Todo) {
 			"cursor", "pointer",
 		)
 
-		if Clicked(checkbox) {
-			todo.Completed = !todo.Completed
-			Rerender()
-		}
-
 		if todo.Completed {
-			Raw(svgCheckedCheckbox)
+			UnsafeRaw(svgCheckedCheckbox)
 		} else {
-			Raw(svgEmptyCheckbox)
+			UnsafeRaw(svgEmptyCheckbox)
 		}
 	})
 
+	if toggled {
+		store.Dispatch(ToggleTodo{Id: todo.Id})
+	}
+
 	Div(func() {
 		textbox := "text-" + item
 		Id(textbox)
@@ -339,26 +400,33 @@ func DrawNormalTodo(item string, editTodo string, todo *Todo) {
 			"transition", "color 0.4s",
 		)
 
+		now := timers.Now()
+		overdue := !todo.Completed && todo.Due.Overdue(now)
+
 		if todo.Completed {
 			Style(
 				"color", "#d9d9d9",
 				"text-decoration", "line-through",
 			)
+		} else if overdue {
+			Style("color", "#af5b5e")
 		}
 
 		if DoubleClicked(textbox) {
-			editingTodoId = todo.Id
-			Focus(editTodo)
-			Rerender()
+			startEditing(todo.Id)
 		}
 
 		Text(todo.Text)
+
+		if !todo.Due.IsZero() {
+			DrawDue(item, todo, overdue, now)
+		}
 	})
 
-	Div(func() {
-		destroy := "destroy-" + item
-		Id(destroy)
+	DrawMoveButton(item, todo)
 
+	destroy := "destroy-" + item
+	destroyed := Button(destroy, "Delete "+todo.Text, func() {
 		Style(
 			"text-align", "center",
 			"cursor", "pointer",
@@ -369,16 +437,6 @@ func DrawNormalTodo(item string, editTodo string, todo *Todo) {
 			"height", "58px",
 		)
 
-		if Clicked(destroy) {
-			for i := range todos {
-				if todos[i].Id == todo.Id {
-					todos = append(todos[:i], todos[i+1:]...)
-					break
-				}
-			}
-			Rerender()
-		}
-
 		Div(func() {
 			Style(
 				"position", "absolute",
@@ -394,16 +452,58 @@ func DrawNormalTodo(item string, editTodo string, todo *Todo) {
 				Style("color", "#af5b5e")
 			}
 
-			if Hovering(item) {
+			// keyboard users need to see it too
+			if Hovering(item) || Focused(destroy) {
 				Style("display", "block")
 			}
 
 			Text("×")
 		})
 	})
+
+	if destroyed {
+		store.Dispatch(DeleteTodo{Id: todo.Id})
+	}
+}
+
+// DrawDue draws when todo is due, clicking it removes the due date
+func DrawDue(item string, todo Todo, overdue bool, now time.Time) {
+	due := "due-" + item
+	removed := Button(due, "Due "+todo.Due.Label(now)+", remove the due date", func() {
+		Style(
+			"display", "inline-block",
+			"margin-left", "10px",
+			"padding", "1px 6px",
+			"font-size", "14px",
+			"color", "#777",
+			"border", "1px solid #e6e6e6",
+			"border-radius", "3px",
+			"vertical-align", "middle",
+			"cursor", "pointer",
+		)
+
+		if overdue {
+			Style(
+				"color", "#fff",
+				"background", "#af5b5e",
+				"border-color", "#af5b5e",
+			)
+		}
+
+		Text(todo.Due.Label(now))
+		if Hovering(due) || Focused(due) {
+			Text(" ×")
+		}
+	})
+
+	if removed {
+		store.Dispatch(SetDue{Id: todo.Id})
+	}
 }
 
 func DrawFooter() {
+	list := currentList()
+
 	// pattern below footer
 	Div(func() {
 		Style(
@@ -433,17 +533,11 @@ func DrawFooter() {
 				"text-align", "left",
 			)
 
-			remaining := 0
-			for _, todo := range todos {
-				if !todo.Completed {
-					remaining++
-				}
-			}
-
-			if remaining == 1 {
+			left := remaining(list.Todos)
+			if left == 1 {
 				Text("1 item left")
 			} else {
-				Text(fmt.Sprintf("%d items left", remaining))
+				Text(fmt.Sprintf("%d items left", left))
 			}
 		})
 
@@ -456,14 +550,13 @@ func DrawFooter() {
 			)
 
 			createFilterButton := func(name, filter string) {
-				Div(func() {
-					button := "filter-" + filter
-					Id(button)
-
+				button := "filter-" + filter
+				Link(button, listPath(list.Id, filter), func() {
 					Style(
 						"display", "inline",
 						"margin", "3px",
 						"padding", "3px 7px",
+						"color", "inherit",
 						"text-decoration", "none",
 						"border", "1px solid transparent",
 						"border-radius", "3",
@@ -476,11 +569,7 @@ func DrawFooter() {
 
 					if getActiveFilter() == filter {
 						Style("border-color", "rgba(175, 47, 47, 0.2)")
-					}
-
-					if Clicked(button) {
-						setActiveFilter(filter)
-						Rerender()
+						Attr("aria-current", "page")
 					}
 
 					Text(name)
@@ -490,10 +579,11 @@ func DrawFooter() {
 			createFilterButton("All", filterAll)
 			createFilterButton("Active", filterActive)
 			createFilterButton("Completed", filterCompleted)
+			createFilterButton("Due today", filterDueToday)
 		})
 
 		anyCompleted := false
-		for _, todo := range todos {
+		for _, todo := range list.Todos {
 			if todo.Completed {
 				anyCompleted = true
 				break
@@ -501,10 +591,8 @@ func DrawFooter() {
 		}
 
 		if anyCompleted {
-			Div(func() {
-				button := "clear-completed"
-				Id(button)
-
+			button := "clear-completed"
+			cleared := Button(button, "", func() {
 				Style(
 					"display", "inline",
 					"float", "right",
@@ -518,19 +606,183 @@ func DrawFooter() {
 					Style("text-decoration", "underline")
 				}
 
-				if Clicked(button) {
-					newTodos := []Todo{}
-					for _, todo := range todos {
-						if !todo.Completed {
-							newTodos = append(newTodos, todo)
-						}
-					}
-					todos = newTodos
-					Rerender()
-				}
-
 				Text("Clear Completed")
 			})
+
+			if cleared {
+				store.Dispatch(ClearCompleted{ListId: list.Id})
+			}
+		}
+	})
+}
+
+var shortcutHelp = [][2]string{
+	{"j / ↓", "Next todo"},
+	{"k / ↑", "Previous todo"},
+	{"space / x", "Toggle the highlighted todo"},
+	{"enter / e", "Edit the highlighted todo"},
+	{"delete / d", "Delete the highlighted todo"},
+	{"m", "Move the highlighted todo to another list"},
+	{"C", "Clear completed todos"},
+	{"esc", "Stop highlighting"},
+	{"ctrl z", "Undo"},
+	{"ctrl shift z", "Redo"},
+	{"?", "Show or hide this help"},
+}
+
+// HandleShortcuts moves the highlighted todo and acts on it, the shortcuts only apply when focus isn't in a field or on a button
+func HandleShortcuts() {
+	// ask for every shortcut every frame, even ones that have nothing to act on yet
+	down := Shortcut("j", "ArrowDown")
+	up := Shortcut("k", "ArrowUp")
+	toggle := Shortcut(" ", "x")
+	edit := Shortcut("Enter", "e")
+	remove := Shortcut("Delete", "Backspace", "d")
+	move := Shortcut("m")
+	clear := Shortcut("C")
+	help := Shortcut("?")
+	escape := Shortcut("Escape")
+
+	if help {
+		showHelp = !showHelp
+		Rerender()
+	}
+
+	if clear {
+		store.Dispatch(ClearCompleted{ListId: currentList().Id})
+	}
+
+	todos := currentList().Todos
+	visible := visibleTodos(todos)
+	current := -1
+	for i, index := range visible {
+		if todos[index].Id == highlightedTodoId {
+			current = i
+			break
+		}
+	}
+
+	if escape {
+		highlightedTodoId = -1
+		Rerender()
+		return
+	}
+
+	if (down || up) && len(visible) > 0 {
+		switch {
+		case down && current < len(visible)-1:
+			current++
+		case up && current > 0:
+			current--
+		case current == -1:
+			current = 0
+		}
+		highlightedTodoId = todos[visible[current]].Id
+		ScrollIntoView(todoItemId(highlightedTodoId))
+		Rerender()
+		return
+	}
+
+	if current == -1 {
+		return
+	}
+	todo := todos[visible[current]]
+
+	switch {
+	case toggle:
+		store.Dispatch(ToggleTodo{Id: todo.Id})
+	case edit:
+		startEditing(todo.Id)
+	case move && len(store.State().Lists) > 1:
+		toggleMoveMenu(todo.Id)
+	case remove:
+		// keep a highlight so that several todos can be deleted in a row
+		switch {
+		case current < len(visible)-1:
+			highlightedTodoId = todos[visible[current+1]].Id
+		case current > 0:
+			highlightedTodoId = todos[visible[current-1]].Id
+		default:
+			highlightedTodoId = -1
+		}
+		store.Dispatch(DeleteTodo{Id: todo.Id})
+	}
+}
+
+func DrawHelp() {
+	if !showHelp {
+		return
+	}
+
+	Div(func() {
+		help := "help"
+		Id(help)
+		Attr("role", "dialog", "aria-modal", "true", "aria-label", "Keyboard shortcuts")
+		TrapFocus(help)
+
+		Style(
+			"position", "fixed",
+			"top", "50%",
+			"left", "50%",
+			"transform", "translate(-50%, -50%)",
+			"width", "360px",
+			"padding", "20px",
+			"background", "#fff",
+			"box-shadow", "0 2px 4px 0 rgba(0, 0, 0, 0.2), 0 25px 50px 0 rgba(0, 0, 0, 0.1)",
+			"font-size", "14px",
+			"z-index", "10",
+		)
+
+		if Keyup(help, keyEsc) {
+			showHelp = false
+			Rerender()
+		}
+
+		Tag("h2", func() {
+			Style(
+				"margin", "0px 0px 12px 0px",
+				"font-size", "18px",
+				"font-weight", "400",
+			)
+
+			Text("Keyboard shortcuts")
+		})
+
+		for _, line := range shortcutHelp {
+			Div(func() {
+				Style("padding", "3px 0px")
+
+				Tag("kbd", func() {
+					Style(
+						"display", "inline-block",
+						"width", "110px",
+						"font-family", "monospace",
+						"color", "#af5b5e",
+					)
+
+					Text(line[0])
+				})
+
+				Text(line[1])
+			})
+		}
+
+		closed := Button("help-close", "", func() {
+			Style(
+				"display", "inline-block",
+				"margin-top", "12px",
+				"padding", "3px 7px",
+				"border", "1px solid rgba(175, 47, 47, 0.2)",
+				"border-radius", "3px",
+				"cursor", "pointer",
+			)
+
+			Text("Close")
+		})
+
+		if closed {
+			showHelp = false
+			Rerender()
 		}
 	})
 }
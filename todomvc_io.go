@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/todoio"
+)
+
+var (
+	// whether an import replaces the todos instead of merging into them
+	importReplace = false
+	// why the last import failed, or why it did nothing
+	importMessage = ""
+)
+
+func toIO(todos []Todo) []todoio.Todo {
+	converted := []todoio.Todo{}
+	for _, todo := range todos {
+		converted = append(converted, todoio.Todo{Id: todo.Id, Text: todo.Text, Completed: todo.Completed, Due: todo.Due})
+	}
+	return converted
+}
+
+func fromIO(todos []todoio.Todo) []Todo {
+	converted := []Todo{}
+	for _, todo := range todos {
+		converted = append(converted, Todo{Id: todo.Id, Text: todo.Text, Completed: todo.Completed, Due: todo.Due})
+	}
+	return converted
+}
+
+// importTodos dispatches the todos in f, or sets importMessage if there is nothing to import
+func importTodos(f File) {
+	format, ok := todoio.FormatOf(f.Name)
+	if !ok {
+		importMessage = f.Name + " isn't a .json, .csv or .txt file"
+		Rerender()
+		return
+	}
+
+	imported, err := todoio.Parse(f.Data, format)
+	if err != nil {
+		importMessage = f.Name + ": " + err.Error()
+		Rerender()
+		return
+	}
+
+	mode := todoio.Merge
+	if importReplace {
+		mode = todoio.Replace
+	}
+	list := currentList()
+	result := todoio.Import(toIO(list.Todos), imported, store.State().NextId, mode)
+	if mode == todoio.Merge && result.Added == 0 && result.Updated == 0 {
+		importMessage = f.Name + " has nothing that isn't already here"
+		Rerender()
+		return
+	}
+
+	summary := "Imported " + plural(result.Added, "todo")
+	if result.Updated > 0 {
+		summary += ", updated " + strconv.Itoa(result.Updated)
+	}
+	if mode == todoio.Replace {
+		summary = "Replaced the todos with " + plural(result.Added, "imported todo")
+	}
+
+	importMessage = ""
+	store.Dispatch(ImportTodos{ListId: list.Id, Todos: fromIO(result.Todos), NextId: result.NextId, Summary: summary})
+}
+
+// fileName makes a list name safe to use as a file name, "Sprint 42" becomes "sprint-42"
+func fileName(name string) string {
+	b := []byte{}
+	for _, c := range []byte(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			b = append(b, c)
+		case c >= 'A' && c <= 'Z':
+			b = append(b, c-'A'+'a')
+		case len(b) > 0 && b[len(b)-1] != '-':
+			b = append(b, '-')
+		}
+	}
+	for len(b) > 0 && b[len(b)-1] == '-' {
+		b = b[:len(b)-1]
+	}
+	if len(b) == 0 {
+		return "todos"
+	}
+	return string(b)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// DrawImportExport draws links to download the todos and a file input to load them from a file
+func DrawImportExport() {
+	Div(func() {
+		Id("import-export")
+
+		Style(
+			"margin", "20px auto 0",
+			"color", "#777",
+			"font-size", "12px",
+			"text-align", "center",
+		)
+
+		Div(func() {
+			Text("Export as ")
+
+			for _, format := range todoio.Formats {
+				button := "export-" + format.Extension()[1:]
+				exported := Button(button, "Export as "+format.String(), func() {
+					Style(
+						"display", "inline",
+						"margin", "0px 4px",
+						"text-decoration", "underline",
+						"cursor", "pointer",
+					)
+
+					Text(format.String())
+				})
+
+				if exported {
+					list := currentList()
+					Download(File{
+						Name: fileName(list.Name) + format.Extension(),
+						Type: format.MediaType(),
+						Data: todoio.Export(toIO(list.Todos), format),
+					})
+				}
+			}
+		})
+
+		Div(func() {
+			Style("margin-top", "8px")
+
+			Tag("label", func() {
+				Attr("for", "import-file")
+
+				Text("Import ")
+			})
+
+			if f, ok := FileInput("import-file", ".json,.csv,.txt"); ok {
+				importTodos(f)
+			}
+
+			toggled := ToggleButton("import-replace", "", importReplace, func() {
+				Style(
+					"display", "inline",
+					"padding", "1px 5px",
+					"border", "1px solid transparent",
+					"border-radius", "3px",
+					"cursor", "pointer",
+				)
+
+				if importReplace {
+					Style("border-color", "rgba(175, 47, 47, 0.2)")
+				}
+
+				Text("Replace existing todos")
+			})
+
+			if toggled {
+				importReplace = !importReplace
+				Rerender()
+			}
+		})
+
+		if importMessage != "" {
+			Div(func() {
+				Id("import-message")
+				Attr("role", "alert")
+
+				Style(
+					"margin-top", "8px",
+					"color", "#af5b5e",
+				)
+
+				Text(importMessage)
+			})
+		}
+	})
+}
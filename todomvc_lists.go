@@ -0,0 +1,284 @@
+package main
+
+import "strconv"
+
+var (
+	// the list whose name is being edited, -1 for none
+	renamingListId = -1
+	// the todo whose move menu is open, -1 for none
+	movingTodoId = -1
+)
+
+// currentList is the list in the path, as in /lists/3/active, or the first one if the path doesn't name one that exists
+func currentList() TodoList {
+	lists := store.State().Lists
+	params, ok := Route("/lists/:id/:filter")
+	if !ok {
+		params, ok = Route("/lists/:id")
+	}
+	if ok {
+		id, err := strconv.Atoi(params["id"])
+		if i := listIndex(lists, id); err == nil && i != -1 {
+			return lists[i]
+		}
+	}
+	return lists[0]
+}
+
+func listPath(id int, filter string) string {
+	return "/lists/" + strconv.Itoa(id) + "/" + filter
+}
+
+func remaining(todos []Todo) int {
+	n := 0
+	for _, todo := range todos {
+		if !todo.Completed {
+			n++
+		}
+	}
+	return n
+}
+
+// DrawLists draws a tab for every list with how many todos are left in it, double-clicking a tab renames the list
+func DrawLists() {
+	state := store.State()
+	current := currentList()
+
+	Tag("nav", func() {
+		Id("lists")
+		Attr("aria-label", "Lists")
+
+		Style(
+			"margin", "20px auto 0",
+			"text-align", "center",
+			"font-size", "14px",
+			"color", "#777",
+		)
+
+		for _, list := range state.Lists {
+			tab := "list-" + strconv.Itoa(list.Id)
+			rename := "rename-" + tab
+
+			if list.Id == renamingListId {
+				Tag("input", func() {
+					Id(rename)
+					Attr("value", list.Name, "aria-label", "List name")
+
+					Style(
+						"margin", "3px",
+						"padding", "3px 7px",
+						"font-size", "14px",
+						"border", "1px solid #999",
+					)
+
+					if Keyup(rename, keyEnter) {
+						renamingListId = -1
+						if name := InputValues[rename]; name != "" {
+							store.Dispatch(RenameList{Id: list.Id, Name: name})
+						}
+						Rerender()
+					} else if Keyup(rename, keyEsc) || !Focused(rename) {
+						renamingListId = -1
+						Rerender()
+					}
+				})
+				continue
+			}
+
+			Link(tab, listPath(list.Id, getActiveFilter()), func() {
+				Style(
+					"display", "inline-block",
+					"margin", "3px",
+					"padding", "3px 7px",
+					"color", "inherit",
+					"text-decoration", "none",
+					"border", "1px solid transparent",
+					"border-radius", "3px",
+				)
+
+				if Hovering(tab) {
+					Style("border-color", "rgba(175, 47, 47, 0.1)")
+				}
+
+				if list.Id == current.Id {
+					Style("border-color", "rgba(175, 47, 47, 0.2)")
+					Attr("aria-current", "page")
+				}
+
+				if DoubleClicked(tab) {
+					renamingListId = list.Id
+					Focus(rename)
+					Rerender()
+				}
+
+				Text(list.Name + " ")
+				Tag("span", func() {
+					Style("color", "#af5b5e")
+
+					Text(strconv.Itoa(remaining(list.Todos)))
+				})
+			})
+
+			if list.Id == current.Id && len(state.Lists) > 1 {
+				deleted := Button("delete-"+tab, "Delete the list "+list.Name, func() {
+					Style(
+						"display", "inline",
+						"margin-right", "6px",
+						"cursor", "pointer",
+					)
+
+					Text("×")
+				})
+
+				if deleted {
+					store.Dispatch(DeleteList{Id: list.Id})
+					Navigate("/")
+				}
+			}
+		}
+
+		added := Button("new-list", "New list", func() {
+			Style(
+				"display", "inline",
+				"margin", "3px",
+				"padding", "3px 7px",
+				"cursor", "pointer",
+			)
+
+			Text("+")
+		})
+
+		if added {
+			id := state.NextListId
+			store.Dispatch(AddList{Name: "List " + strconv.Itoa(len(state.Lists)+1)})
+			Navigate(listPath(id, filterAll))
+			renamingListId = id
+			Focus("rename-list-" + strconv.Itoa(id))
+		}
+	})
+}
+
+// DrawMoveButton draws a button that opens a menu of the other lists, choosing one moves todo there
+func DrawMoveButton(item string, todo Todo) {
+	lists := store.State().Lists
+	if len(lists) < 2 {
+		return
+	}
+
+	move := "move-" + item
+	opened := Button(move, "Move "+todo.Text+" to another list", func() {
+		Style(
+			"position", "absolute",
+			"top", "0px",
+			"right", "50px",
+			"width", "30px",
+			"height", "58px",
+			"text-align", "center",
+			"cursor", "pointer",
+		)
+
+		Div(func() {
+			Style(
+				"padding-top", "17px",
+				"font-size", "20px",
+				"color", "#cc9a9a",
+				"display", "none",
+			)
+
+			if Hovering(item) || Focused(move) || movingTodoId == todo.Id {
+				Style("display", "block")
+			}
+
+			Text("→")
+		})
+	})
+
+	if opened {
+		toggleMoveMenu(todo.Id)
+	}
+
+	if movingTodoId != todo.Id {
+		return
+	}
+
+	Div(func() {
+		menu := "move-menu-" + item
+		Id(menu)
+		Attr("role", "menu", "aria-label", "Move to")
+
+		Style(
+			"position", "absolute",
+			"top", "50px",
+			"right", "10px",
+			"padding", "4px 0px",
+			"background", "#fff",
+			"box-shadow", "0 2px 4px 0 rgba(0, 0, 0, 0.2)",
+			"font-size", "14px",
+			"z-index", "3",
+		)
+
+		if Keyup(menu, keyEsc) {
+			movingTodoId = -1
+			Focus(move)
+			Rerender()
+		}
+
+		// the menu closes when focus goes somewhere else
+		inMenu := Focused(move)
+
+		_, from := findTodo(lists, todo.Id)
+		for _, list := range lists {
+			if list.Id == lists[from].Id {
+				continue
+			}
+
+			option := menu + "-" + strconv.Itoa(list.Id)
+			chosen := Button(option, "", func() {
+				Attr("role", "menuitem")
+				Style(
+					"padding", "4px 16px",
+					"cursor", "pointer",
+				)
+
+				if Focused(option) {
+					inMenu = true
+				}
+				if Hovering(option) || Focused(option) {
+					Style("background", "#f6f6f6")
+				}
+
+				Text(list.Name)
+			})
+
+			if chosen {
+				movingTodoId = -1
+				store.Dispatch(MoveTodo{Id: todo.Id, ListId: list.Id})
+			}
+		}
+
+		if !inMenu && movingTodoId == todo.Id {
+			movingTodoId = -1
+			Rerender()
+		}
+	})
+}
+
+// toggleMoveMenu opens the move menu of the todo with id, focusing its first option, or closes it if it is open
+func toggleMoveMenu(id int) {
+	if movingTodoId == id {
+		movingTodoId = -1
+		Rerender()
+		return
+	}
+
+	movingTodoId = id
+	lists := store.State().Lists
+	_, from := findTodo(lists, id)
+	for _, list := range lists {
+		if list.Id != lists[from].Id {
+			Focus("move-menu-" + todoItemId(id) + "-" + strconv.Itoa(list.Id))
+			break
+		}
+	}
+	Rerender()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+)
+
+// Storage keeps strings between page loads, see browserStorage
+type Storage interface {
+	Get(key string) (string, bool)
+	Set(key string, value string)
+	Remove(key string)
+}
+
+// every list is saved under its own key, next to an index of the lists
+const storageKey = "todomvc-gopherjs-im"
+
+func indexKey() string {
+	return storageKey + "/lists"
+}
+
+func listKey(id int) string {
+	return storageKey + "/list/" + strconv.Itoa(id)
+}
+
+var (
+	// print every action to the console along with the todos after it
+	LogActions = false
+)
+
+func logActions(s *Store, next func(Action)) func(Action) {
+	return func(action Action) {
+		next(action)
+		if LogActions {
+			print(fmt.Sprintf("%T %+v -> %+v", action, action, s.State().Lists))
+		}
+	}
+}
+
+// persistLists is middleware that saves the lists whenever they change, only rewriting the ones that did
+func persistLists(storage Storage) Middleware {
+	return func(s *Store, next func(Action)) func(Action) {
+		return func(action Action) {
+			before := s.State()
+			next(action)
+			after := s.State()
+			if sameLists(before.Lists, after.Lists) && before.NextId == after.NextId {
+				return
+			}
+
+			if index := encodeIndex(after); index != encodeIndex(before) {
+				storage.Set(indexKey(), index)
+			}
+			for _, list := range after.Lists {
+				i := listIndex(before.Lists, list.Id)
+				if i == -1 || !sameTodos(before.Lists[i].Todos, list.Todos) {
+					storage.Set(listKey(list.Id), encodeTodos(list.Todos))
+				}
+			}
+			for _, list := range before.Lists {
+				if listIndex(after.Lists, list.Id) == -1 {
+					storage.Remove(listKey(list.Id))
+				}
+			}
+		}
+	}
+}
+
+// loadStorage starts over from the lists saved in storage, a web worker has no storage of its own until its host sends
+// what it has saved, see RunWorker
+func loadStorage(storage Storage) {
+	store = newStore(storage)
+}
+
+// restoreLists returns state with the lists that were saved in storage, if there are any
+func restoreLists(storage Storage, state AppState) AppState {
+	saved, ok := storage.Get(indexKey())
+	if !ok {
+		return state
+	}
+
+	restored, err := decodeIndex(saved)
+	if err != nil {
+		// better to start over than to fail to start
+		print("discarding saved lists:", err.Error())
+		return state
+	}
+	for i, list := range restored.Lists {
+		saved, ok := storage.Get(listKey(list.Id))
+		if !ok {
+			continue
+		}
+		todos, err := decodeTodos(saved)
+		if err != nil {
+			print("discarding saved todos of list", list.Id, err.Error())
+			continue
+		}
+		restored.Lists[i].Todos = todos
+		for _, todo := range todos {
+			if todo.Id >= restored.NextId {
+				restored.NextId = todo.Id + 1
+			}
+		}
+	}
+	return restored
+}
+
+// encodeIndex writes {"nextId":3,"nextListId":1,"lists":[{"id":0,"name":"Todos"}]}
+func encodeIndex(state AppState) string {
+	w := &jsonWriter{}
+	w.Raw(`{"nextId":`)
+	w.Int(state.NextId)
+	w.Raw(`,"nextListId":`)
+	w.Int(state.NextListId)
+	w.Raw(`,"lists":[`)
+	for i, list := range state.Lists {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Raw(`{"id":`)
+		w.Int(list.Id)
+		w.Raw(`,"name":`)
+		w.String(list.Name)
+		w.Raw("}")
+	}
+	w.Raw("]}")
+	return w.Done()
+}
+
+// decodeIndex returns the lists without their todos
+func decodeIndex(s string) (AppState, error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return AppState{}, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return AppState{}, DecodeError("saved lists are not an object")
+	}
+	lists, ok := m["lists"].([]interface{})
+	if !ok || len(lists) == 0 {
+		return AppState{}, DecodeError("saved lists have no lists")
+	}
+
+	state := AppState{NextId: jsonInt(m, "nextId"), NextListId: jsonInt(m, "nextListId")}
+	for _, e := range lists {
+		l, ok := e.(map[string]interface{})
+		if !ok {
+			return AppState{}, DecodeError("saved list is not an object")
+		}
+		list := TodoList{Id: jsonInt(l, "id"), Name: jsonString(l, "name"), Todos: []Todo{}}
+		if list.Id >= state.NextListId {
+			state.NextListId = list.Id + 1
+		}
+		state.Lists = append(state.Lists, list)
+	}
+	return state, nil
+}
+
+// encodeTodos writes {"todos":[{"id":0,"text":"hello0","completed":true}]}
+func encodeTodos(todos []Todo) string {
+	w := &jsonWriter{}
+	w.Raw(`{"todos":`)
+	writeTodos(w, todos)
+	w.Raw("}")
+	return w.Done()
+}
+
+func writeTodos(w *jsonWriter, todos []Todo) {
+	w.Raw("[")
+	for i, todo := range todos {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Raw(`{"id":`)
+		w.Int(todo.Id)
+		w.Raw(`,"text":`)
+		w.String(todo.Text)
+		w.Raw(`,"completed":`)
+		w.Bool(todo.Completed)
+		if !todo.Due.IsZero() {
+			w.Raw(`,"due":`)
+			w.String(todo.Due.String())
+		}
+		w.Raw("}")
+	}
+	w.Raw("]")
+}
+
+func decodeTodos(s string) ([]Todo, error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, DecodeError("saved todos are not an object")
+	}
+	list, ok := m["todos"].([]interface{})
+	if !ok {
+		return nil, DecodeError("saved todos have no list")
+	}
+	return readTodos(list)
+}
+
+func readTodos(list []interface{}) ([]Todo, error) {
+	todos := []Todo{}
+	for _, e := range list {
+		t, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, DecodeError("saved todo is not an object")
+		}
+		todo := Todo{Id: jsonInt(t, "id"), Text: jsonString(t, "text"), Completed: jsonBool(t, "completed")}
+		// a due date that can't be read isn't worth losing the todo over
+		if due, err := duedate.ParseString(jsonString(t, "due"), time.Local); err == nil {
+			todo.Due = due
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
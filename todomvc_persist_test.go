@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+)
+
+type memStorage map[string]string
+
+func (m memStorage) Get(key string) (string, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+func (m memStorage) Set(key string, value string) {
+	m[key] = value
+}
+
+func (m memStorage) Remove(key string) {
+	delete(m, key)
+}
+
+func TestPersistDueDates(t *testing.T) {
+	newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	due := duedate.Due{At: time.Date(2026, 10, 21, 17, 30, 0, 0, time.Local), HasTime: true}
+
+	m := memStorage{}
+	store = newStore(m)
+	store.Dispatch(AddTodo{ListId: 0, Text: "rent", Due: due})
+	todos := newStore(m).State().Lists[0].Todos
+	if len(todos) != 4 || !todos[3].Due.Equal(due) || !todos[0].Due.IsZero() {
+		t.Fatal(todos)
+	}
+}
+
+func TestRestoreBadDueDate(t *testing.T) {
+	newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+
+	m := memStorage{
+		indexKey(): `{"lists":[{"id":0,"name":"Todos"}],"nextId":3,"nextListId":1}`,
+		listKey(0): `{"todos":[{"id":1,"text":"rent","completed":false,"due":"next tuesday"},{"id":2,"text":"b","completed":true,"due":"2026-10-21"}]}`,
+	}
+	todos := newStore(m).State().Lists[0].Todos
+	if len(todos) != 2 || todos[0].Text != "rent" || !todos[0].Due.IsZero() || todos[1].Due.String() != "2026-10-21" {
+		t.Fatal(todos)
+	}
+}
+
+// TestWorkerStorage has a web worker start from what its host saved and send the host its changes
+func TestWorkerStorage(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	saved := memStorage{}
+	newStore(saved).Dispatch(AddTodo{ListId: 0, Text: "saved"})
+	storage := func() string {
+		w := &jsonWriter{}
+		w.Raw(`{"type":"storage","items":`)
+		w.StringMap(saved)
+		w.Raw("}")
+		return w.Done()
+	}
+
+	// a server doesn't take it
+	a.backend.Receive(storage())
+	if todos := store.State().Lists[0].Todos; len(todos) != 1 {
+		t.Fatalf("the server took a client's todos %v", todos)
+	}
+
+	a.backend.acceptStorage = true
+	a.backend.Receive(storage())
+	todos := store.State().Lists[0].Todos
+	if len(todos) != 4 || todos[3].Text != "saved" {
+		t.Fatalf("the worker didn't start from the saved todos %v", todos)
+	}
+
+	a.out = nil
+	store.Dispatch(ToggleTodo{Id: todos[3].Id})
+	sent := 0
+	for _, msg := range a.out {
+		if !strings.Contains(msg, `"type":"save"`) {
+			continue
+		}
+		v, err := parseJSON(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := v.(map[string]interface{})
+		saved.Set(jsonString(m, "key"), jsonString(m, "value"))
+		sent++
+	}
+	if sent == 0 {
+		t.Fatalf("the change wasn't sent to the host %v", a.out)
+	}
+	if todos := newStore(saved).State().Lists[0].Todos; !todos[3].Completed {
+		t.Fatal("what the worker sent doesn't restore the change")
+	}
+}
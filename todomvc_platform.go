@@ -0,0 +1,27 @@
+//go:build !js
+// +build !js
+
+package main
+
+import "time"
+
+// the server keeps its todos in memory, shared by every session, so there is nothing to restore
+func appStorage() Storage {
+	return nil
+}
+
+// serverTimers call back in the session that set them, see inSession
+type serverTimers struct{}
+
+func (serverTimers) Now() time.Time {
+	return time.Now()
+}
+
+func (serverTimers) AfterFunc(d time.Duration, f func()) func() {
+	t := time.AfterFunc(d, inSession(f))
+	return func() { t.Stop() }
+}
+
+func appTimers() Timers {
+	return serverTimers{}
+}
@@ -0,0 +1,54 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"time"
+
+	"github.com/gopherjs/gopherjs/js"
+)
+
+type browserStorage struct {
+	localStorage *js.Object
+}
+
+// appStorage is localStorage, or nil where there isn't one, as in a web worker, which is sent its host's instead
+func appStorage() Storage {
+	localStorage := js.Global.Get("localStorage")
+	if localStorage == js.Undefined || localStorage == nil {
+		return nil
+	}
+	return browserStorage{localStorage}
+}
+
+func (s browserStorage) Get(key string) (string, bool) {
+	value := s.localStorage.Call("getItem", key)
+	if value == nil {
+		return "", false
+	}
+	return value.String(), true
+}
+
+func (s browserStorage) Set(key string, value string) {
+	s.localStorage.Call("setItem", key, value)
+}
+
+func (s browserStorage) Remove(key string) {
+	s.localStorage.Call("removeItem", key)
+}
+
+type browserTimers struct{}
+
+func (browserTimers) Now() time.Time {
+	return time.Now()
+}
+
+func (browserTimers) AfterFunc(d time.Duration, f func()) func() {
+	t := time.AfterFunc(d, f)
+	return func() { t.Stop() }
+}
+
+func appTimers() Timers {
+	return browserTimers{}
+}
@@ -0,0 +1,180 @@
+package main
+
+import "time"
+
+// a recording starts with the app's state so that replaying it renders the same trees as when it was recorded, see
+// StartRecording and Replay
+
+// saveAppState writes the lists along with everything the view keeps about them, like the undo history and whether
+// the help is open
+func saveAppState() string {
+	state := store.State()
+	w := &jsonWriter{}
+	w.Raw(`{"nextId":`)
+	w.Int(state.NextId)
+	w.Raw(`,"nextListId":`)
+	w.Int(state.NextListId)
+	w.Raw(`,"editingId":`)
+	w.Int(editingId)
+	w.Raw(`,"lists":`)
+	writeLists(w, state.Lists)
+	w.Raw(`,"undo":`)
+	writeUndoEntries(w, undoStack)
+	w.Raw(`,"redo":`)
+	writeUndoEntries(w, redoStack)
+	w.Raw(`,"toast":`)
+	w.String(toast)
+	w.Raw(`,"highlightedTodoId":`)
+	w.Int(highlightedTodoId)
+	w.Raw(`,"showHelp":`)
+	w.Bool(showHelp)
+	w.Raw(`,"renamingListId":`)
+	w.Int(renamingListId)
+	w.Raw(`,"movingTodoId":`)
+	w.Int(movingTodoId)
+	w.Raw(`,"importReplace":`)
+	w.Bool(importReplace)
+	w.Raw(`,"importMessage":`)
+	w.String(importMessage)
+	w.Raw("}")
+	return w.Done()
+}
+
+// loadAppState replaces the app's state with what saveAppState wrote and returns how to put the lists and the undo
+// history back, the view state goes along with the rest of the ui state, see Replay. The loaded lists aren't saved as
+// they change, a replay mustn't overwrite the user's todos.
+func loadAppState(s string) (func(), error) {
+	v, err := parseJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, DecodeError("recorded state is not an object")
+	}
+	lists, err := readLists(m["lists"])
+	if err != nil {
+		return nil, err
+	}
+	undo, err := readUndoEntries(m["undo"])
+	if err != nil {
+		return nil, err
+	}
+	redo, err := readUndoEntries(m["redo"])
+	if err != nil {
+		return nil, err
+	}
+
+	savedStore := store
+	savedUndo, savedRedo := undoStack, redoStack
+	restore := func() {
+		// the wake up was for the loaded todos
+		stopWake()
+		store = savedStore
+		undoStack, redoStack = savedUndo, savedRedo
+	}
+
+	store = newStore(nil)
+	store.state = AppState{
+		Lists:      lists,
+		NextId:     jsonInt(m, "nextId"),
+		NextListId: jsonInt(m, "nextListId"),
+	}
+	editingId = jsonInt(m, "editingId")
+	undoStack, redoStack, toast = undo, redo, jsonString(m, "toast")
+	highlightedTodoId, showHelp = jsonInt(m, "highlightedTodoId"), jsonBool(m, "showHelp")
+	renamingListId, movingTodoId = jsonInt(m, "renamingListId"), jsonInt(m, "movingTodoId")
+	importReplace, importMessage = jsonBool(m, "importReplace"), jsonString(m, "importMessage")
+	return restore, nil
+}
+
+// appNow is the time the app renders at, it is recorded with every frame
+func appNow() time.Time {
+	return timers.Now()
+}
+
+// useRecordedClock has the app read the time from now until restore is called, see Replay
+func useRecordedClock(now func() time.Time) (restore func()) {
+	saved := timers
+	timers = recordedTimers{now: now}
+	return func() {
+		timers = saved
+	}
+}
+
+// recordedTimers never call back, a replay's frames come from the recording rather than from what it schedules
+type recordedTimers struct {
+	now func() time.Time
+}
+
+func (t recordedTimers) Now() time.Time {
+	return t.now()
+}
+
+func (recordedTimers) AfterFunc(d time.Duration, f func()) func() {
+	return func() {}
+}
+
+func writeLists(w *jsonWriter, lists []TodoList) {
+	w.Raw("[")
+	for i, list := range lists {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.Raw(`{"id":`)
+		w.Int(list.Id)
+		w.Raw(`,"name":`)
+		w.String(list.Name)
+		w.Raw(`,"todos":`)
+		writeTodos(w, list.Todos)
+		w.Raw("}")
+	}
+	w.Raw("]")
+}
+
+func readLists(v interface{}) ([]TodoList, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil, DecodeError("recorded lists are not an array")
+	}
+	lists := []TodoList{}
+	var err error
+	for _, e := range a {
+		l, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, DecodeError("recorded list is not an object")
+		}
+		todos, _ := l["todos"].([]interface{})
+		list := TodoList{Id: jsonInt(l, "id"), Name: jsonString(l, "name")}
+		list.Todos, err = readTodos(todos)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+func writeUndoEntries(w *jsonWriter, entries []undoEntry) {
+	w.Raw("[")
+	for i, entry := range entries {
+		if i > 0 {
+			w.Raw(",")
+		}
+		writeLists(w, entry.lists)
+	}
+	w.Raw("]")
+}
+
+func readUndoEntries(v interface{}) ([]undoEntry, error) {
+	a, _ := v.([]interface{})
+	entries := []undoEntry{}
+	for _, e := range a {
+		lists, err := readLists(e)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, undoEntry{lists: lists})
+	}
+	return entries, nil
+}
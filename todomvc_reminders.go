@@ -0,0 +1,64 @@
+package main
+
+import "time"
+
+// Timers is the clock the app reads due dates against and schedules reminders with, tests swap in a fake one
+type Timers interface {
+	Now() time.Time
+	// AfterFunc calls f once d has passed unless stop is called first
+	AfterFunc(d time.Duration, f func()) (stop func())
+}
+
+var (
+	timers = appTimers()
+
+	// reminders only go off for due times after the first frame, not for everything that was already overdue
+	remindersSince time.Time
+	// the reminder time each todo was last reminded about, a todo whose due date changes is reminded again
+	reminded = map[int]time.Time{}
+
+	// the next time HandleReminders wants a frame, and how to cancel it
+	wakeAt   time.Time
+	stopWake = func() {}
+)
+
+// HandleReminders notifies about todos that have come due since the last frame, and asks for a frame at the next
+// reminder or at midnight, when the overdue styles and the due today filter change
+func HandleReminders() {
+	now := timers.Now()
+	if remindersSince.IsZero() {
+		remindersSince = now
+	}
+	y, m, d := now.Date()
+	next := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+
+	for _, list := range store.State().Lists {
+		for _, todo := range list.Todos {
+			if todo.Completed || todo.Due.IsZero() {
+				continue
+			}
+
+			at := todo.Due.RemindAt()
+			if at.After(now) {
+				if at.Before(next) {
+					next = at
+				}
+				continue
+			}
+
+			if last, ok := reminded[todo.Id]; ok && last.Equal(at) {
+				continue
+			}
+			reminded[todo.Id] = at
+			if at.After(remindersSince) {
+				Notify(todo.Text, "Due "+todo.Due.Label(now))
+			}
+		}
+	}
+
+	if !next.Equal(wakeAt) {
+		stopWake()
+		wakeAt = next
+		stopWake = timers.AfterFunc(next.Sub(now), Rerender)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+)
+
+// manualTimers only moves when told to
+type manualTimers struct {
+	now     time.Time
+	pending []*manualTimer
+}
+
+type manualTimer struct {
+	at time.Time
+	f  func()
+}
+
+func (t *manualTimers) Now() time.Time {
+	return t.now
+}
+
+func (t *manualTimers) AfterFunc(d time.Duration, f func()) func() {
+	timer := &manualTimer{at: t.now.Add(d), f: f}
+	t.pending = append(t.pending, timer)
+	return func() {
+		for i, p := range t.pending {
+			if p == timer {
+				t.pending = append(t.pending[:i], t.pending[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// advance moves the clock on by d, calling the functions that come due on the way in order
+func (t *manualTimers) advance(d time.Duration) {
+	end := t.now.Add(d)
+	for {
+		sort.SliceStable(t.pending, func(i, j int) bool { return t.pending[i].at.Before(t.pending[j].at) })
+		if len(t.pending) == 0 || t.pending[0].at.After(end) {
+			break
+		}
+		timer := t.pending[0]
+		t.pending = t.pending[1:]
+		t.now = timer.at
+		timer.f()
+	}
+	t.now = end
+}
+
+// useManualTimers swaps in manual timers starting at now until the test ends
+func useManualTimers(t *testing.T, now time.Time) *manualTimers {
+	fake := &manualTimers{now: now}
+	saved := timers
+	timers = fake
+	t.Cleanup(func() {
+		timers = saved
+		remindersSince, reminded, wakeAt, stopWake = time.Time{}, map[int]time.Time{}, time.Time{}, func() {}
+	})
+	return fake
+}
+
+func TestManualTimers(t *testing.T) {
+	fake := &manualTimers{now: time.Unix(0, 0)}
+	var fired []int
+	fake.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	stop := fake.AfterFunc(time.Second, func() { fired = append(fired, 1) })
+	fake.AfterFunc(time.Second, func() {
+		fired = append(fired, 3)
+		fake.AfterFunc(time.Second, func() { fired = append(fired, 4) })
+	})
+	stop()
+	fake.advance(1500 * time.Millisecond)
+	fake.advance(time.Second)
+	if len(fired) != 3 || fired[0] != 3 || fired[1] != 2 || fired[2] != 4 {
+		t.Fatal(fired)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	start := time.Date(2026, 10, 19, 8, 0, 0, 0, time.Local)
+	fake := useManualTimers(t, start)
+	a := newTestApp(t, []Todo{
+		{Id: 0, Text: "old", Due: duedate.Day(start.AddDate(0, 0, -2))},
+		{Id: 1, Text: "later"},
+	})
+
+	a.frame("", nil)
+	// already overdue on the first frame, so no reminder
+	if !strings.Contains(a.last(), `"notify":[]`) {
+		t.Fatal(a.last())
+	}
+
+	a.input(InputEvent{Type: eventKeyup, Id: "new-todo", Code: keyEnter})
+	a.frame("", map[string]string{"new-todo": "pay rent today at 10am"})
+	// adding a due date is the moment to ask to notify, browsers won't ask from a frame a timer asked for
+	if !strings.Contains(a.last(), `"asknotify":true`) {
+		t.Fatal(a.last())
+	}
+	a.frame("", nil)
+	if !strings.Contains(a.last(), `"asknotify":false`) {
+		t.Fatal("asked again")
+	}
+	todos := currentList().Todos
+	if len(todos) != 3 || todos[2].Text != "pay rent" || todos[2].Due.String() != "2026-10-19T10:00" {
+		t.Fatal(todos)
+	}
+	if !wakeAt.Equal(start.Add(2 * time.Hour)) {
+		t.Fatal("next wakeup at", wakeAt)
+	}
+
+	// the wakeup asks for a frame, which reminds
+	fake.advance(2 * time.Hour)
+	a.frame("", nil)
+	if !strings.Contains(a.last(), `"notify":[{"title":"pay rent","body":"Due today 10am"}],"asknotify":false`) {
+		t.Fatal(a.last())
+	}
+	a.frame("", nil)
+	if !strings.Contains(a.last(), `"notify":[]`) {
+		t.Fatal("reminded twice")
+	}
+
+	// a new due date is reminded about again
+	store.Dispatch(SetDue{Id: todos[2].Id, Due: duedate.Due{At: fake.Now().Add(time.Hour), HasTime: true}})
+	a.frame("", nil)
+	fake.advance(time.Hour)
+	a.frame("", nil)
+	if !strings.Contains(a.last(), `"title":"pay rent"`) {
+		t.Fatal(a.last())
+	}
+
+	// completed todos aren't
+	store.Dispatch(SetDue{Id: todos[1].Id, Due: duedate.Due{At: fake.Now().Add(time.Hour), HasTime: true}})
+	store.Dispatch(ToggleTodo{Id: todos[1].Id})
+	a.frame("", nil)
+	fake.advance(time.Hour)
+	a.frame("", nil)
+	if !strings.Contains(a.last(), `"notify":[]`) {
+		t.Fatal(a.last())
+	}
+}
+
+func TestOverdueAtMidnight(t *testing.T) {
+	start := time.Date(2026, 10, 19, 22, 0, 0, 0, time.Local)
+	fake := useManualTimers(t, start)
+	a := newTestApp(t, []Todo{
+		{Id: 0, Text: "today", Due: duedate.Day(start)},
+		{Id: 1, Text: "tomorrow", Due: duedate.Day(start.AddDate(0, 0, 1))},
+		{Id: 2, Text: "done", Completed: true, Due: duedate.Day(start)},
+		{Id: 3, Text: "someday"},
+	})
+
+	a.frame("#/lists/0/today", nil)
+	if v := visibleTodos(currentList().Todos); len(v) != 1 || v[0] != 0 {
+		t.Fatal("due today", v)
+	}
+	if strings.Contains(a.last(), `"background:#af5b5e;`) {
+		t.Fatal("overdue before midnight")
+	}
+
+	// nothing to remind about today, the next frame is at midnight when "today" becomes overdue
+	midnight := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	if !wakeAt.Equal(midnight) {
+		t.Fatal("next wakeup at", wakeAt)
+	}
+
+	rerendered := false
+	a.backend.send = func(m string) {
+		a.out = append(a.out, m)
+		rerendered = rerendered || strings.Contains(m, messageRequestFrame)
+	}
+	fake.advance(2 * time.Hour)
+	if !rerendered {
+		t.Fatal("no frame at midnight")
+	}
+	a.frame("#/lists/0/today", nil)
+	if v := visibleTodos(currentList().Todos); len(v) != 2 || v[0] != 0 || v[1] != 1 {
+		t.Fatal("due today after midnight", v)
+	}
+	if !strings.Contains(a.last(), `"background:#af5b5e;`) {
+		t.Fatal("not overdue after midnight")
+	}
+	if !wakeAt.Equal(midnight.Add(9 * time.Hour)) {
+		t.Fatal("next wakeup at", wakeAt)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import "github.com/christopherhesse/todomvc-gopherjs-im/duedate"
+
+// the app's state lives in a store: render reads State() and event handlers Dispatch actions, which go through the
+// middleware and then the reducer. Subscribers run after every action that reaches the reducer.
+//
+// the filter isn't kept here, it belongs to the url and every session of the server has its own. Neither is which todo
+// is being edited, that is view state, see viewState.
+
+type TodoList struct {
+	Id    int
+	Name  string
+	Todos []Todo
+}
+
+type AppState struct {
+	Lists []TodoList
+	// todo ids are unique across lists so that a todo keeps its id when it moves
+	NextId     int
+	NextListId int
+}
+
+// actions, the reducer ignores ones it doesn't know, such as Undo when there is no undo middleware
+
+type Action interface{}
+
+type AddTodo struct {
+	ListId int
+	Text   string
+	Due    duedate.Due
+}
+type ToggleTodo struct{ Id int }
+type SetAllCompleted struct {
+	ListId    int
+	Completed bool
+}
+type RenameTodo struct {
+	Id   int
+	Text string
+	// replaces the due date unless it's zero
+	Due duedate.Due
+}
+
+// SetDue sets a todo's due date, the zero Due removes it
+type SetDue struct {
+	Id  int
+	Due duedate.Due
+}
+type DeleteTodo struct{ Id int }
+type ClearCompleted struct{ ListId int }
+
+// MoveTodo puts a todo at the end of another list
+type MoveTodo struct {
+	Id     int
+	ListId int
+}
+
+type AddList struct{ Name string }
+type RenameList struct {
+	Id   int
+	Name string
+}
+
+// DeleteList does nothing to the last list, there is always one
+type DeleteList struct{ Id int }
+
+// ReplaceLists swaps in every list, for restoring saved or undone lists
+type ReplaceLists struct{ Lists []TodoList }
+
+// ImportTodos swaps in a list's todos after they were imported from a file, it can be undone like any other change
+type ImportTodos struct {
+	ListId int
+	Todos  []Todo
+	NextId int
+	// shown in the toast
+	Summary string
+}
+
+type Reducer func(state AppState, action Action) AppState
+
+// Middleware wraps the dispatch of everything after it, it can pass actions on to next, change them, drop them or
+// dispatch others. The store is there for reading the state before and after next.
+type Middleware func(store *Store, next func(Action)) func(Action)
+
+type Store struct {
+	state       AppState
+	dispatch    func(Action)
+	subscribers []func()
+}
+
+// NewStore runs actions through middleware in order, the first one sees them first
+func NewStore(state AppState, reducer Reducer, middleware ...Middleware) *Store {
+	s := &Store{state: state}
+	s.dispatch = func(action Action) {
+		s.state = reducer(s.state, action)
+		for _, f := range s.subscribers {
+			f()
+		}
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		s.dispatch = middleware[i](s, s.dispatch)
+	}
+	return s
+}
+
+// State must not be modified, the reducer makes new slices rather than changing the ones it was given
+func (s *Store) State() AppState {
+	return s.state
+}
+
+func (s *Store) Dispatch(action Action) {
+	s.dispatch(action)
+}
+
+func (s *Store) Subscribe(f func()) {
+	s.subscribers = append(s.subscribers, f)
+}
+
+// reduce returns the state unchanged, slices included, when an action has nothing to do so that middleware can tell
+func reduce(state AppState, action Action) AppState {
+	switch a := action.(type) {
+	case AddTodo:
+		if listIndex(state.Lists, a.ListId) == -1 {
+			break
+		}
+		todo := Todo{Id: state.NextId, Text: a.Text, Due: a.Due}
+		state.NextId++
+		state.Lists = updateList(state.Lists, a.ListId, func(todos []Todo) []Todo {
+			// the cap forces a copy
+			return append(todos[:len(todos):len(todos)], todo)
+		})
+	case ToggleTodo:
+		state.Lists = updateAllTodos(state.Lists, func(todo *Todo) bool {
+			if todo.Id != a.Id {
+				return false
+			}
+			todo.Completed = !todo.Completed
+			return true
+		})
+	case SetAllCompleted:
+		state.Lists = updateList(state.Lists, a.ListId, func(todos []Todo) []Todo {
+			return updateTodos(todos, func(todo *Todo) bool {
+				if todo.Completed == a.Completed {
+					return false
+				}
+				todo.Completed = a.Completed
+				return true
+			})
+		})
+	case RenameTodo:
+		state.Lists = updateAllTodos(state.Lists, func(todo *Todo) bool {
+			if todo.Id != a.Id || todo.Text == a.Text && (a.Due.IsZero() || todo.Due.Equal(a.Due)) {
+				return false
+			}
+			todo.Text = a.Text
+			if !a.Due.IsZero() {
+				todo.Due = a.Due
+			}
+			return true
+		})
+	case SetDue:
+		state.Lists = updateAllTodos(state.Lists, func(todo *Todo) bool {
+			if todo.Id != a.Id || todo.Due.Equal(a.Due) {
+				return false
+			}
+			todo.Due = a.Due
+			return true
+		})
+	case DeleteTodo:
+		state.Lists = updateLists(state.Lists, func(list *TodoList) bool {
+			todos := filterTodos(list.Todos, func(todo Todo) bool { return todo.Id != a.Id })
+			if sameTodos(todos, list.Todos) {
+				return false
+			}
+			list.Todos = todos
+			return true
+		})
+	case ClearCompleted:
+		state.Lists = updateList(state.Lists, a.ListId, func(todos []Todo) []Todo {
+			return filterTodos(todos, func(todo Todo) bool { return !todo.Completed })
+		})
+	case MoveTodo:
+		todo, from := findTodo(state.Lists, a.Id)
+		if from == -1 || state.Lists[from].Id == a.ListId || listIndex(state.Lists, a.ListId) == -1 {
+			break
+		}
+		state.Lists = reduce(state, DeleteTodo{Id: a.Id}).Lists
+		state.Lists = updateList(state.Lists, a.ListId, func(todos []Todo) []Todo {
+			return append(todos[:len(todos):len(todos)], todo)
+		})
+	case AddList:
+		list := TodoList{Id: state.NextListId, Name: a.Name, Todos: []Todo{}}
+		state.NextListId++
+		state.Lists = append(state.Lists[:len(state.Lists):len(state.Lists)], list)
+	case RenameList:
+		state.Lists = updateLists(state.Lists, func(list *TodoList) bool {
+			if list.Id != a.Id || list.Name == a.Name {
+				return false
+			}
+			list.Name = a.Name
+			return true
+		})
+	case DeleteList:
+		if len(state.Lists) > 1 {
+			kept := []TodoList{}
+			for _, list := range state.Lists {
+				if list.Id != a.Id {
+					kept = append(kept, list)
+				}
+			}
+			if len(kept) != len(state.Lists) {
+				state.Lists = kept
+			}
+		}
+	case ReplaceLists:
+		state.Lists = a.Lists
+		for _, list := range a.Lists {
+			if list.Id >= state.NextListId {
+				state.NextListId = list.Id + 1
+			}
+			for _, todo := range list.Todos {
+				if todo.Id >= state.NextId {
+					state.NextId = todo.Id + 1
+				}
+			}
+		}
+	case ImportTodos:
+		state.Lists = updateList(state.Lists, a.ListId, func([]Todo) []Todo { return a.Todos })
+		if a.NextId > state.NextId {
+			state.NextId = a.NextId
+		}
+	}
+	return state
+}
+
+// updateLists copies lists if update changes any of them, update reports whether it did
+func updateLists(lists []TodoList, update func(list *TodoList) bool) []TodoList {
+	var updated []TodoList
+	for i := range lists {
+		list := lists[i]
+		if !update(&list) {
+			continue
+		}
+		if updated == nil {
+			updated = append([]TodoList(nil), lists...)
+		}
+		updated[i] = list
+	}
+	if updated == nil {
+		return lists
+	}
+	return updated
+}
+
+// updateList replaces the todos of the list with id by what update returns, update returns its argument to change nothing
+func updateList(lists []TodoList, id int, update func(todos []Todo) []Todo) []TodoList {
+	return updateLists(lists, func(list *TodoList) bool {
+		if list.Id != id {
+			return false
+		}
+		todos := update(list.Todos)
+		if sameTodos(todos, list.Todos) {
+			return false
+		}
+		list.Todos = todos
+		return true
+	})
+}
+
+// updateAllTodos runs updateTodos on every list
+func updateAllTodos(lists []TodoList, update func(todo *Todo) bool) []TodoList {
+	return updateLists(lists, func(list *TodoList) bool {
+		todos := updateTodos(list.Todos, update)
+		if sameTodos(todos, list.Todos) {
+			return false
+		}
+		list.Todos = todos
+		return true
+	})
+}
+
+// updateTodos copies todos if update changes any of them, update reports whether it did
+func updateTodos(todos []Todo, update func(todo *Todo) bool) []Todo {
+	var updated []Todo
+	for i := range todos {
+		todo := todos[i]
+		if !update(&todo) {
+			continue
+		}
+		if updated == nil {
+			updated = append([]Todo(nil), todos...)
+		}
+		updated[i] = todo
+	}
+	if updated == nil {
+		return todos
+	}
+	return updated
+}
+
+func filterTodos(todos []Todo, keep func(todo Todo) bool) []Todo {
+	kept := []Todo{}
+	for _, todo := range todos {
+		if keep(todo) {
+			kept = append(kept, todo)
+		}
+	}
+	if len(kept) == len(todos) {
+		return todos
+	}
+	return kept
+}
+
+// sameLists is sameTodos for lists
+func sameLists(a []TodoList, b []TodoList) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// sameTodos reports whether a and b are the same slice, which is enough to know nothing changed since the reducer
+// never modifies a slice in place
+func sameTodos(a []Todo, b []Todo) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+func listIndex(lists []TodoList, id int) int {
+	for i := range lists {
+		if lists[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// findTodo returns the todo with id and the index of its list, which is -1 if there is no such todo
+func findTodo(lists []TodoList, id int) (Todo, int) {
+	for i, list := range lists {
+		for _, todo := range list.Todos {
+			if todo.Id == id {
+				return todo, i
+			}
+		}
+	}
+	return Todo{}, -1
+}
@@ -0,0 +1,138 @@
+package main
+
+import "testing"
+
+// testApp renders the app into a message backend, see newTestApp
+type testApp struct {
+	backend *messageBackend
+	// the messages the app sent, the last one is the latest frame
+	out []string
+}
+
+// newTestApp starts the app over with one list of todos and nothing else, everything is put back when the test ends
+func newTestApp(t testing.TB, todos []Todo) *testApp {
+	return newTestAppLists(t, []TodoList{{Id: 0, Name: "Todos", Todos: todos}})
+}
+
+func newTestAppLists(t testing.TB, lists []TodoList) *testApp {
+	savedStore := store
+	savedUndo, savedRedo := undoStack, redoStack
+	store = newStore(nil)
+	undoStack, redoStack = nil, nil
+	store.state.Lists = lists
+	store.state.NextId = 100
+	store.state.NextListId = lists[len(lists)-1].Id + 1
+
+	a := &testApp{}
+	a.backend = newMessageBackend(func(m string) { a.out = append(a.out, m) }, func() float64 { return 0 })
+	savedUI := saveUIState()
+	loadUIState(newUIState(a.backend))
+
+	// the view state goes along with the ui state
+	t.Cleanup(func() {
+		loadUIState(savedUI)
+		store = savedStore
+		undoStack, redoStack = savedUndo, savedRedo
+	})
+	return a
+}
+
+// frame renders a frame at location with the given input values
+func (a *testApp) frame(location string, values map[string]string) {
+	if values == nil {
+		values = map[string]string{}
+	}
+	a.backend.Receive(eventMessage(messageFrame, InputEvent{Type: eventFrame, Values: values, Location: location}))
+}
+
+func (a *testApp) input(ev InputEvent) {
+	a.backend.Receive(eventMessage(messageInput, ev))
+}
+
+func (a *testApp) last() string {
+	return a.out[len(a.out)-1]
+}
+
+func TestHelpShortcut(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	a.frame("", nil)
+
+	a.input(InputEvent{Type: eventShortcut, Id: "?"})
+	a.frame("", nil)
+	a.frame("", nil)
+	if !showHelp || focusId != "help-close" {
+		t.Fatalf("help didn't open, showHelp %v, focus %q", showHelp, focusId)
+	}
+
+	// focus is on the close button now, which must not keep "?" from closing the help again, see TestKeyTaken
+	a.input(InputEvent{Type: eventShortcut, Id: "?"})
+	a.frame("", nil)
+	if showHelp {
+		t.Fatal("help didn't close")
+	}
+}
+
+func TestUndoAfterDelete(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}, {Id: 1, Text: "b"}})
+	a.frame("", nil)
+
+	a.input(InputEvent{Type: eventClick, Id: "destroy-todo-item-0"})
+	a.frame("", nil)
+	a.frame("", nil)
+	if len(currentList().Todos) != 1 {
+		t.Fatal(currentList().Todos)
+	}
+
+	// focus fell back to a button, chords still reach the app from there, see TestKeyTaken
+	a.input(InputEvent{Type: eventShortcut, Id: "Ctrl+z"})
+	a.frame("", nil)
+	if len(currentList().Todos) != 2 || toast != "" {
+		t.Fatal(currentList().Todos, toast)
+	}
+	a.input(InputEvent{Type: eventShortcut, Id: "Ctrl+Z"})
+	a.frame("", nil)
+	if len(currentList().Todos) != 1 {
+		t.Fatal(currentList().Todos)
+	}
+}
+
+// BenchmarkFrame is the garbage a frame of 1,000 todos leaves behind when nothing changes and when one todo does,
+// with and without recycling vnodes
+func BenchmarkFrame(b *testing.B) {
+	todos := []Todo{}
+	for i := 0; i < 1000; i++ {
+		todos = append(todos, Todo{Id: i, Text: "todo " + itoa(i), Completed: i%3 == 0})
+	}
+
+	for _, pooling := range []bool{true, false} {
+		name := "pooled"
+		if !pooling {
+			name = "unpooled"
+		}
+		for _, toggle := range []bool{false, true} {
+			bench := name + "/unchanged"
+			if toggle {
+				bench = name + "/toggle"
+			}
+			b.Run(bench, func(b *testing.B) {
+				savedPooling := Pooling
+				Pooling = pooling
+				defer func() { Pooling = savedPooling }()
+
+				a := newTestApp(b, todos)
+				// the first frames fill the pool and the style cache
+				a.frame("#/lists/0/all", nil)
+				a.frame("#/lists/0/all", nil)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if toggle {
+						store.Dispatch(ToggleTodo{Id: 500})
+					}
+					a.frame("#/lists/0/all", nil)
+					a.out = a.out[:0]
+				}
+			})
+		}
+	}
+}
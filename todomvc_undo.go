@@ -0,0 +1,172 @@
+package main
+
+import "strconv"
+
+// undoHistory is middleware that keeps the lists from before every action that changed them, Undo and Redo actions
+// step through them
+
+const undoLimit = 100
+
+type Undo struct{}
+type Redo struct{}
+
+// DismissToast hides the toast without undoing anything
+type DismissToast struct{}
+
+type undoEntry struct {
+	lists []TodoList
+}
+
+var (
+	undoStack []undoEntry
+	redoStack []undoEntry
+
+	// what the toast says about the last destructive action or import, empty when there is no toast
+	toast = ""
+)
+
+func undoHistory(s *Store, next func(Action)) func(Action) {
+	step := func(from *[]undoEntry, to *[]undoEntry) {
+		if len(*from) == 0 {
+			return
+		}
+		entry := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		*to = append(*to, undoEntry{lists: s.State().Lists})
+		toast = ""
+		next(ReplaceLists{Lists: entry.lists})
+	}
+
+	return func(action Action) {
+		switch action.(type) {
+		case Undo:
+			step(&undoStack, &redoStack)
+			return
+		case Redo:
+			step(&redoStack, &undoStack)
+			return
+		case DismissToast:
+			// passed on so that subscribers hear about it, the reducer has nothing to do
+			toast = ""
+			next(action)
+			return
+		case ReplaceLists:
+			// undoing a replace would be surprising, if it came from somewhere else the history no longer applies
+			undoStack, redoStack, toast = nil, nil, ""
+			next(action)
+			return
+		}
+
+		before := s.State().Lists
+		next(action)
+		if sameLists(before, s.State().Lists) {
+			return
+		}
+
+		undoStack = append(undoStack, undoEntry{lists: before})
+		if len(undoStack) > undoLimit {
+			undoStack = undoStack[len(undoStack)-undoLimit:]
+		}
+		redoStack = nil
+		toast = destructiveName(action, before)
+	}
+}
+
+// destructiveName describes actions that lose todos, and imports, for the toast. It's empty for everything else.
+func destructiveName(action Action, before []TodoList) string {
+	switch a := action.(type) {
+	case ImportTodos:
+		return a.Summary
+	case DeleteTodo:
+		if todo, i := findTodo(before, a.Id); i != -1 {
+			return "Deleted " + strconv.Quote(todo.Text)
+		}
+	case DeleteList:
+		if i := listIndex(before, a.Id); i != -1 {
+			return "Deleted the list " + strconv.Quote(before[i].Name)
+		}
+	case ClearCompleted:
+		removed := 0
+		if i := listIndex(before, a.ListId); i != -1 {
+			for _, todo := range before[i].Todos {
+				if todo.Completed {
+					removed++
+				}
+			}
+		}
+		if removed == 1 {
+			return "Cleared 1 completed todo"
+		}
+		return "Cleared " + strconv.Itoa(removed) + " completed todos"
+	}
+	return ""
+}
+
+func HandleUndoShortcuts() {
+	undo := Shortcut("Ctrl+z")
+	redo := Shortcut("Ctrl+Z", "Ctrl+y")
+
+	if undo {
+		store.Dispatch(Undo{})
+	}
+	if redo {
+		store.Dispatch(Redo{})
+	}
+}
+
+// DrawToast offers to undo the last destructive action until something else is done
+func DrawToast() {
+	if toast == "" {
+		return
+	}
+	Div(func() {
+		Id("toast")
+		Attr("role", "status", "aria-live", "polite")
+
+		Style(
+			"position", "fixed",
+			"bottom", "20px",
+			"left", "50%",
+			"transform", "translateX(-50%)",
+			"padding", "10px 16px",
+			"background", "#333",
+			"color", "#fff",
+			"font-size", "14px",
+			"border-radius", "3px",
+			"box-shadow", "0 2px 4px 0 rgba(0, 0, 0, 0.2)",
+			"z-index", "5",
+		)
+
+		Tag("span", toast)
+
+		undone := Button("toast-undo", "", func() {
+			Style(
+				"display", "inline",
+				"margin-left", "16px",
+				"color", "#f0a8aa",
+				"font-weight", "bold",
+				"cursor", "pointer",
+			)
+
+			Text("Undo")
+		})
+
+		dismissed := Button("toast-dismiss", "Dismiss", func() {
+			Style(
+				"display", "inline",
+				"margin-left", "12px",
+				"color", "#aaa",
+				"cursor", "pointer",
+			)
+
+			Text("×")
+		})
+
+		if undone {
+			store.Dispatch(Undo{})
+		}
+		if dismissed {
+			store.Dispatch(DismissToast{})
+		}
+	})
+}
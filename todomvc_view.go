@@ -0,0 +1,65 @@
+package main
+
+import "time"
+
+// viewState is what the app keeps about how one user sees the todos rather than about the todos themselves, the
+// server swaps it in and out with the rest of a session's ui state so that one user opening the help or renaming a
+// list doesn't do it for everyone
+type viewState struct {
+	editingId         int
+	highlightedTodoId int
+	showHelp          bool
+	renamingListId    int
+	movingTodoId      int
+	importReplace     bool
+	importMessage     string
+	toast             string
+
+	remindersSince time.Time
+	reminded       map[int]time.Time
+	wakeAt         time.Time
+	stopWake       func()
+}
+
+func newViewState() viewState {
+	return viewState{
+		editingId:         -1,
+		highlightedTodoId: -1,
+		renamingListId:    -1,
+		movingTodoId:      -1,
+		reminded:          map[int]time.Time{},
+		stopWake:          func() {},
+	}
+}
+
+func saveViewState() viewState {
+	return viewState{
+		editingId:         editingId,
+		highlightedTodoId: highlightedTodoId,
+		showHelp:          showHelp,
+		renamingListId:    renamingListId,
+		movingTodoId:      movingTodoId,
+		importReplace:     importReplace,
+		importMessage:     importMessage,
+		toast:             toast,
+		remindersSince:    remindersSince,
+		reminded:          reminded,
+		wakeAt:            wakeAt,
+		stopWake:          stopWake,
+	}
+}
+
+func loadViewState(v viewState) {
+	editingId = v.editingId
+	highlightedTodoId = v.highlightedTodoId
+	showHelp = v.showHelp
+	renamingListId = v.renamingListId
+	movingTodoId = v.movingTodoId
+	importReplace = v.importReplace
+	importMessage = v.importMessage
+	toast = v.toast
+	remindersSince = v.remindersSince
+	reminded = v.reminded
+	wakeAt = v.wakeAt
+	stopWake = v.stopWake
+}
@@ -10,6 +10,9 @@ var (
 	hoverable       map[string]bool
 	keyupable       map[string]bool
 	keyupableCodes  map[string][]int
+	scrollable      map[string]bool
+	linkable        map[string]bool
+	shortcuts       map[string]bool
 
 	InputValues = map[string]string{}
 
@@ -19,15 +22,27 @@ var (
 	focusSelection = [2]int{}
 	keyupId        = ""
 	keyupCode      = 0
+	shortcutKey    = ""
+	revealIds      = []string{}
 	hoverIds       = []string{}
+	location       = ""
+	scrollTops     = map[string]float64{}
+
+	// set when frames are rendered somewhere else, such as a web worker
+	forwardInput func(InputEvent)
+
+	// keyCode of a key that triggered a shortcut, until it is released
+	suppressedKeyup = 0
+
+	PreviousRoot *VNode
 )
 
 func Rerender() {
-	js.Global.Get("window").Call("requestAnimationFrame", Frame)
+	backend.RequestFrame()
 }
 
 func Frame() {
-	startMs := js.Global.Get("Date").Call("now").Int()
+	p := beginProfile()
 
 	rendering = true
 
@@ -37,83 +52,155 @@ func Frame() {
 	hoverable = map[string]bool{}
 	keyupable = map[string]bool{}
 	keyupableCodes = map[string][]int{}
-
-	// store values for inputs
-	inputs := js.Global.Get("document").Call("getElementsByTagName", "input")
-	for i := 0; i < inputs.Length(); i++ {
-		input := inputs.Index(i)
-		id := input.Get("id").String()
-		if id != "" {
-			InputValues[id] = input.Get("value").String()
-		}
+	scrollable = map[string]bool{}
+	linkable = map[string]bool{}
+	shortcuts = map[string]bool{}
+	uploadable = map[string]bool{}
+	focusTrap = ""
+	revealIds = []string{}
+	downloads = []File{}
+	notifications = []Notification{}
+	askNotify = false
+
+	// store values for inputs and selection
+	values, selection, ok := backend.Snapshot(focusId)
+	InputValues = values
+	if ok {
+		focusSelection = selection
 	}
+	location = backend.Location()
+	rects = backend.Rects()
+	measured = map[string]bool{}
+	record(InputEvent{Type: eventFrame, Values: copyValues(values), Selection: selection, HasSelection: ok, Location: location, Rects: copyRects(rects), Now: appNow()})
 
-	// store selection
-	if focusId != "" {
-		elem := js.Global.Get("document").Call("getElementById", focusId)
-		if elem != nil {
-			focusSelection[0] = elem.Get("selectionStart").Int()
-			focusSelection[1] = elem.Get("selectionEnd").Int()
-		}
-	}
+	p.InputMs = now() - p.StartMs
 
+	renderStart := now()
 	render()
+	p.RenderMs = now() - renderStart - p.DiffMs - p.PatchMs
 
-	// restore values for inputs
-	for id, value := range InputValues {
-		elem := js.Global.Get("document").Call("getElementById", id)
-		if elem != nil {
-			elem.Set("value", value)
-		}
-	}
+	restoreStart := now()
 
-	// set focused element and any selection data
-	if focusId != "" {
-		elem := js.Global.Get("document").Call("getElementById", focusId)
-		if elem != nil {
-			// focus will normally cause the page to scroll, which we don't want, so scroll back afterward
-			x := js.Global.Get("window").Get("scrollX").Int()
-			y := js.Global.Get("window").Get("scrollY").Int()
-			elem.Call("focus")
-			js.Global.Get("window").Call("scrollTo", x, y)
-			if focusSelection == [2]int{-1, -1} {
-				elem.Set("selectionStart", elem.Get("value").Get("length"))
-				elem.Set("selectionEnd", elem.Get("value").Get("length"))
-			} else {
-				elem.Set("selectionStart", focusSelection[0])
-				elem.Set("selectionEnd", focusSelection[1])
-			}
-		}
-	}
+	// restore values for inputs and set focused element and any selection data
+	backend.Restore(InputValues, focusId, focusSelection)
+
+	p.RestoreMs = now() - restoreStart
 
 	// clear user input state
 	clickId = ""
 	doubleClickId = ""
 	keyupId = ""
 	keyupCode = 0
+	shortcutKey = ""
+	uploadId = ""
+	upload = File{}
 	InputValues = map[string]string{}
 	focusSelection = [2]int{-1, -1}
 
 	rendering = false
 
-	endMs := js.Global.Get("Date").Call("now").Int()
-	print("render", endMs-startMs, "ms")
+	backend.Flush()
+
+	endProfile(p)
+}
+
+// Commit diffs the tree returned by Done against the previous frame's tree and applies the result through the backend
+func Commit(root *VNode) {
+	patches := DiffNodes(PreviousRoot, root)
+	// for the debug overlay, whichever backend applies them
+	lastPatches = patches
+	backend.Patch(patches)
+	backend.ScrollIntoView(revealIds)
+	backend.Download(downloads)
+	backend.Notify(notifications, askNotify)
+	backend.Measure(sortedKeys(measured))
+
+	updateFocus(PreviousRoot, root)
+
+	// patches only refer to the new tree, so the old one can be reused for the next frame
+	if Pooling && PreviousRoot != nil && !PreviousRoot.retained {
+		releaseVNode(PreviousRoot)
+	}
+	PreviousRoot = root
+	commits++
+
+	runAfterPatch()
+}
+
+// Input applies a user input event to the ui state and schedules a frame
+func Input(ev InputEvent) {
+	if forwardInput != nil {
+		// rendering happens elsewhere, but keep enough state here for the event handlers in Setup
+		record(ev)
+		applyInput(ev)
+		forwardInput(ev)
+		return
+	}
+
+	record(ev)
+	applyInput(ev)
+	Rerender()
+}
+
+func applyInput(ev InputEvent) {
+	switch ev.Type {
+	case eventClick:
+		clickId = ev.Id
+	case eventDoubleClick:
+		doubleClickId = ev.Id
+	case eventFocus:
+		focusId = ev.Id
+	case eventBlur:
+		focusId = ""
+	case eventKeyup:
+		keyupId = ev.Id
+		keyupCode = ev.Code
+	case eventHover:
+		hoverIds = ev.Ids
+	case eventScroll:
+		scrollTops[ev.Id] = ev.Scroll
+	case eventShortcut:
+		shortcutKey = ev.Id
+	case eventFile:
+		uploadId = ev.Id
+		upload = ev.File
+	case eventFocusMove:
+		moveFocus(ev.Code)
+	case eventDebug:
+		DebugOverlay = !DebugOverlay
+	}
+}
+
+func Location() string {
+	return location
+}
+
+func SetLocation(l string) {
+	location = l
+	backend.SetLocation(l)
 }
 
 func Setup() {
 	js.Global.Get("document").Call("addEventListener", "click", func(e *js.Object) {
 		ids := findIds(e.Get("target"), clickable)
-		if len(ids) > 0 {
-			clickId = ids[0]
-			Rerender()
+		if len(ids) == 0 {
+			return
 		}
+
+		if linkable[ids[0]] {
+			if browserOpensLink(e.Get("button").Int(), e.Get("ctrlKey").Bool(), e.Get("metaKey").Bool(), e.Get("shiftKey").Bool(), e.Get("altKey").Bool()) {
+				return
+			}
+			e.Call("preventDefault")
+		}
+
+		Input(InputEvent{Type: eventClick, Id: ids[0]})
 	})
 
 	js.Global.Get("document").Call("addEventListener", "dblclick", func(e *js.Object) {
 		ids := findIds(e.Get("target"), doubleClickable)
 		if len(ids) > 0 {
-			doubleClickId = ids[0]
-			Rerender()
+			Input(InputEvent{Type: eventDoubleClick, Id: ids[0]})
 		}
 	})
 
@@ -125,8 +212,7 @@ func Setup() {
 		newFocusId := e.Get("target").Get("id").String()
 
 		if newFocusId != focusId {
-			focusId = newFocusId
-			Rerender()
+			Input(InputEvent{Type: eventFocus, Id: newFocusId})
 		}
 	}, true) // use capture mode because firefox does not support focusin
 
@@ -136,20 +222,59 @@ func Setup() {
 		}
 
 		if focusId != "" {
-			focusId = ""
-			Rerender()
+			Input(InputEvent{Type: eventBlur})
 		}
 	}, true) // use capture mode because firefox does not support focusin
 
 	js.Global.Get("document").Call("addEventListener", "keyup", func(e *js.Object) {
-		ids := findIds(e.Get("target"), keyupable)
-		if len(ids) > 0 {
-			id := ids[0]
+		// the key that triggered a shortcut shouldn't also count as a keyup for whatever has focus now,
+		// like the edit field that enter opens
+		if suppressedKeyup != 0 && e.Get("keyCode").Int() == suppressedKeyup {
+			suppressedKeyup = 0
+			return
+		}
+
+		// the innermost element listening for this key gets it, so a dialog can close on escape while its button has focus
+		for _, id := range findIds(e.Get("target"), keyupable) {
 			for _, keycode := range keyupableCodes[id] {
 				if keycode == e.Get("keyCode").Int() {
-					keyupId = id
-					keyupCode = keycode
-					Rerender()
+					Input(InputEvent{Type: eventKeyup, Id: id, Code: keycode})
+					return
+				}
+			}
+		}
+	})
+
+	js.Global.Get("document").Call("addEventListener", "keydown", func(e *js.Object) {
+		if e.Get("ctrlKey").Bool() && e.Get("shiftKey").Bool() && e.Get("keyCode").Int() == keyD {
+			e.Call("preventDefault")
+			Input(InputEvent{Type: eventDebug})
+		}
+
+		// the browser would happily tab out of a trap
+		if e.Get("keyCode").Int() == keyTab && activeTrap != "" {
+			e.Call("preventDefault")
+			direction := 1
+			if e.Get("shiftKey").Bool() {
+				direction = -1
+			}
+			Input(InputEvent{Type: eventFocusMove, Code: direction})
+		}
+
+		target := e.Get("target")
+		key := shortcutName(e.Get("key").String(), e.Get("ctrlKey").Bool() || e.Get("metaKey").Bool(), e.Get("altKey").Bool(), e.Get("shiftKey").Bool())
+		if shortcuts[key] && !takesKey(target, key) {
+			e.Call("preventDefault")
+			suppressedKeyup = e.Get("keyCode").Int()
+			Input(InputEvent{Type: eventShortcut, Id: key})
+		}
+
+		// widgets activated by space shouldn't also scroll the page, text fields need their spaces though
+		if e.Get("keyCode").Int() == keySpace && target.Get("value") == js.Undefined {
+			for _, keycode := range keyupableCodes[target.Get("id").String()] {
+				if keycode == keySpace {
+					e.Call("preventDefault")
+					break
 				}
 			}
 		}
@@ -171,14 +296,91 @@ func Setup() {
 		}
 
 		if shouldRender {
-			hoverIds = ids
-			Rerender()
+			Input(InputEvent{Type: eventHover, Ids: ids})
+		}
+	})
+
+	js.Global.Get("document").Call("addEventListener", "scroll", func(e *js.Object) {
+		target := e.Get("target")
+		id := target.Get("id")
+		if id == js.Undefined || !scrollable[id.String()] {
+			return
+		}
+
+		Input(InputEvent{Type: eventScroll, Id: id.String(), Scroll: target.Get("scrollTop").Float()})
+	}, true) // use capture mode because scroll events don't bubble
+
+	js.Global.Get("document").Call("addEventListener", "change", func(e *js.Object) {
+		if target := e.Get("target"); uploadable[target.Get("id").String()] {
+			readUpload(target)
 		}
 	})
 
-	js.Global.Get("document").Call("addEventListener", "hashchange", func(e *js.Object) {
-		Rerender()
+	// back and forward only send popstate, typing a new fragment sends hashchange as well, so that one is left alone
+	js.Global.Get("window").Call("addEventListener", "popstate", func(e *js.Object) {
+		Input(InputEvent{Type: eventLocation})
 	})
+
+	exposeRecorder()
+}
+
+// shortcutName is the name Shortcut knows a key press by
+func shortcutName(key string, ctrl bool, alt bool, shift bool) string {
+	// with cmd held, macs report shift+z as "z"
+	if ctrl && shift && len(key) == 1 && key[0] >= 'a' && key[0] <= 'z' {
+		key = string(key[0] - 'a' + 'A')
+	}
+	if alt {
+		key = "Alt+" + key
+	}
+	if ctrl {
+		key = "Ctrl+" + key
+	}
+	return key
+}
+
+// takesKey reports whether element handles key itself, in which case shortcuts leave it alone
+func takesKey(element *js.Object, key string) bool {
+	tag := element.Get("tagName").String()
+	inputType := ""
+	if tag == "INPUT" {
+		inputType = element.Get("type").String()
+	}
+	widget := tag == "BUTTON" || tag == "A" || element.Call("hasAttribute", "tabindex").Bool()
+	return keyTaken(tag, inputType, element.Get("isContentEditable").Bool(), widget, key)
+}
+
+// keyTaken is takesKey for an element described by its tag, its type if it is an input, whether it is
+// contenteditable and whether it is a focusable widget like a button or link. Text entry takes every key, even
+// Ctrl+z which undoes typing there. Widgets only take the keys that activate them, everything else is a shortcut,
+// so that Ctrl+z works right after clicking a delete button.
+func keyTaken(tag string, inputType string, editable bool, widget bool, key string) bool {
+	switch tag {
+	case "TEXTAREA", "SELECT":
+		return true
+	case "INPUT":
+		switch lower(inputType) {
+		case "checkbox", "radio", "button", "submit", "reset", "file", "image", "range", "color":
+			widget = true
+		default:
+			return true
+		}
+	}
+	if editable {
+		return true
+	}
+	return widget && (key == "Enter" || key == " ")
+}
+
+// lower is strings.ToLower for ascii, which is all that tag names and input types need
+func lower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c - 'A' + 'a'
+		}
+	}
+	return string(b)
 }
 
 func findIds(element *js.Object, set map[string]bool) []string {
@@ -213,6 +415,12 @@ func Hovering(id string) bool {
 	return false
 }
 
+// ScrollTop returns how far id has been scrolled, scrolling only causes a frame for elements that asked during the last one
+func ScrollTop(id string) float64 {
+	scrollable[id] = true
+	return scrollTops[id]
+}
+
 func Keyup(id string, keycode int) bool {
 	keyupable[id] = true
 	keyupableCodes[id] = append(keyupableCodes[id], keycode)
@@ -220,6 +428,25 @@ func Keyup(id string, keycode int) bool {
 	return keyupId == id && keyupCode == keycode
 }
 
+// Shortcut reports whether one of keys was pressed this frame while focus wasn't on something that takes keys itself,
+// like a text field, or the enter and space that activate a button. keys are KeyboardEvent.key values such as "j", "ArrowDown", "?" or " ", prefixed with
+// "Ctrl+" (which also matches cmd) and "Alt+" in that order if those are held. Shift shows in the key itself, as in "Ctrl+Z".
+func Shortcut(keys ...string) bool {
+	pressed := false
+	for _, key := range keys {
+		shortcuts[key] = true
+		if shortcutKey == key {
+			pressed = true
+		}
+	}
+	return pressed
+}
+
+// ScrollIntoView scrolls the nearest scrollable ancestors so that id is visible once this frame is applied
+func ScrollIntoView(id string) {
+	revealIds = append(revealIds, id)
+}
+
 func Focus(id string) {
 	focusId = id
 	focusSelection = [2]int{-1, -1}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestKeyTaken(t *testing.T) {
+	tests := []struct {
+		tag       string
+		inputType string
+		editable  bool
+		widget    bool
+		key       string
+		taken     bool
+	}{
+		{"BODY", "", false, false, "j", false},
+		{"BODY", "", false, false, "Enter", false},
+		{"INPUT", "text", false, false, "j", true},
+		{"INPUT", "", false, false, "?", true},
+		{"INPUT", "search", false, false, "Enter", true},
+		{"TEXTAREA", "", false, false, "x", true},
+		{"SELECT", "", false, false, "ArrowDown", true},
+		{"DIV", "", true, false, "d", true},
+		{"BUTTON", "", false, true, "j", false},
+		{"BUTTON", "", false, true, "?", false},
+		{"BUTTON", "", false, true, "Enter", true},
+		{"BUTTON", "", false, true, " ", true},
+		{"A", "", false, true, "k", false},
+		{"DIV", "", false, true, "x", false},
+		{"DIV", "", false, true, " ", true},
+		{"INPUT", "checkbox", false, false, "d", false},
+		{"INPUT", "checkbox", false, false, " ", true},
+		{"INPUT", "CheckBox", false, false, "d", false},
+		{"BUTTON", "", false, true, "Ctrl+z", false},
+		{"INPUT", "text", false, false, "Ctrl+z", true},
+	}
+	for _, test := range tests {
+		if taken := keyTaken(test.tag, test.inputType, test.editable, test.widget, test.key); taken != test.taken {
+			t.Errorf("keyTaken(%q, %q, %v, %v, %q) = %v, want %v", test.tag, test.inputType, test.editable, test.widget, test.key, taken, test.taken)
+		}
+	}
+}
+
+func TestShortcutName(t *testing.T) {
+	tests := []struct {
+		key              string
+		ctrl, alt, shift bool
+		name             string
+	}{
+		{"j", false, false, false, "j"},
+		{"?", false, false, true, "?"},
+		{"z", true, false, false, "Ctrl+z"},
+		{"Z", true, false, true, "Ctrl+Z"},
+		{"z", true, false, true, "Ctrl+Z"},
+		{"x", true, true, false, "Ctrl+Alt+x"},
+		{"ArrowDown", true, false, true, "Ctrl+ArrowDown"},
+	}
+	for _, test := range tests {
+		if name := shortcutName(test.key, test.ctrl, test.alt, test.shift); name != test.name {
+			t.Errorf("shortcutName(%q, %v, %v, %v) = %q, want %q", test.key, test.ctrl, test.alt, test.shift, name, test.name)
+		}
+	}
+}
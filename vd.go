@@ -6,6 +6,8 @@ const (
 	tagText = "_TEXT_"
 	tagRaw  = "_RAW_"
 
+	rawWrapperTag = "raw-html"
+
 	patchReplace         = "replace"
 	patchUpdate          = "update"
 	patchRemoveLastChild = "remove-last-child"
@@ -15,6 +17,28 @@ const (
 var (
 	Root   *VNode
 	Active *VNode
+
+	// trees are recycled once they are no longer on screen, since allocating two maps per node
+	// every frame is most of the garbage under gopherjs
+	Pooling   = true
+	vnodePool []*VNode
+
+	// elements whose content model doesn't allow a raw-html wrapper as a child, see UnsafeRaw
+	rawRestrictedParents = map[string]bool{
+		"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true, "colgroup": true,
+		"ul": true, "ol": true, "dl": true, "select": true, "optgroup": true,
+	}
+
+	// diffs skip subtrees whose hashes match, turned off to measure what that saves
+	hashing = true
+	// scratch space for hashVNode
+	hashKeys []string
+
+	// scratch space and interned strings for serializeStyles
+	styleKeys          []string
+	styleBuf           []byte
+	styleCache         = map[string]string{}
+	previousStyleCache = map[string]string{}
 )
 
 type Patch struct {
@@ -22,6 +46,10 @@ type Patch struct {
 	Location   []int
 	VNode      *VNode
 	Attributes map[string]string
+
+	// the vnode whose DOM node this patch acts on, if it has one PatchDOM uses it instead of walking Location:
+	// the old node for replace and remove-last-child, the node itself for update and the parent for append-child
+	node *VNode
 }
 
 type VNode struct {
@@ -35,10 +63,108 @@ type VNode struct {
 	// used by normal elements
 	Attributes map[string]string
 	Styles     map[string]string
+
+	// structural hash of the tag, data, attributes and children, set when the node is finished
+	hash nodeHash
+
+	// the DOM node this was rendered to, carried forward to the matching vnode of each new frame, nil under the root
+	// of a subtree a diff skipped until findDOM or domOf looks it up
+	dom *js.Object
+
+	// see releaseVNode and Retain
+	released bool
+	retained bool
+	// how many times the vnode has been recycled, see VNodeHandle
+	generation int
 }
 
+// nodeHash is two independent 32 bit hashes, since 64 bit integers are slow under gopherjs, the zero value means not hashed
+type nodeHash [2]uint32
+
 func NewVNode(tag string) *VNode {
-	return &VNode{Tag: tag, Attributes: map[string]string{}, Styles: map[string]string{}}
+	if profile != nil {
+		profile.VNodesCreated++
+	}
+
+	if len(vnodePool) == 0 {
+		return &VNode{Tag: tag, Attributes: map[string]string{}, Styles: map[string]string{}}
+	}
+
+	vnode := vnodePool[len(vnodePool)-1]
+	vnodePool = vnodePool[:len(vnodePool)-1]
+	vnode.Tag = tag
+	vnode.released = false
+	vnode.retained = false
+	return vnode
+}
+
+// releaseVNode returns a tree to the pool once nothing refers to it anymore, except for retained subtrees, which are
+// cut loose from it instead
+func releaseVNode(vnode *VNode) {
+	for _, child := range vnode.Children {
+		if child.retained {
+			child.Parent = nil
+			continue
+		}
+		releaseVNode(child)
+	}
+
+	for k := range vnode.Attributes {
+		delete(vnode.Attributes, k)
+	}
+	for k := range vnode.Styles {
+		delete(vnode.Styles, k)
+	}
+	for i := range vnode.Children {
+		vnode.Children[i] = nil
+	}
+	vnode.Children = vnode.Children[:0]
+	vnode.Parent = nil
+	vnode.Data = ""
+	vnode.hash = nodeHash{}
+	vnode.dom = nil
+	vnode.released = true
+	vnode.generation++
+
+	vnodePool = append(vnodePool, vnode)
+}
+
+// Retain keeps a tree, or a subtree of one, from being recycled after it has been replaced on screen, for instance to
+// compare it against a later frame. A retained subtree loses its Parent when the rest of the tree is recycled.
+func Retain(vnode *VNode) {
+	checkLive(vnode)
+	vnode.retained = true
+}
+
+const recycledPanic = "use of a vnode that has been recycled, call Retain to keep a tree after the next Commit"
+
+func checkLive(vnode *VNode) {
+	if vnode != nil && vnode.released {
+		panic(recycledPanic)
+	}
+}
+
+// VNodeHandle refers to a vnode without keeping it from being recycled. A *VNode kept past the next Commit can end up
+// pointing at a different node once the pool hands it out again, a handle notices.
+type VNodeHandle struct {
+	vnode      *VNode
+	generation int
+}
+
+func HandleOf(vnode *VNode) VNodeHandle {
+	checkLive(vnode)
+	if vnode == nil {
+		return VNodeHandle{}
+	}
+	return VNodeHandle{vnode: vnode, generation: vnode.generation}
+}
+
+// Get returns the vnode, and panics if it has been recycled since the handle was made, even if it is in use again
+func (h VNodeHandle) Get() *VNode {
+	if h.vnode != nil && h.vnode.generation != h.generation {
+		panic(recycledPanic)
+	}
+	return h.vnode
 }
 
 func Init(tag string) {
@@ -51,6 +177,9 @@ func Done() *VNode {
 		panic("not at root element")
 	}
 
+	rotateStyleCache()
+	hashVNode(Root)
+
 	root := Root
 	Root = nil
 	Active = nil
@@ -58,12 +187,25 @@ func Done() *VNode {
 }
 
 func RenderNode(vnode *VNode) *js.Object {
+	checkLive(vnode)
+
+	if profile != nil {
+		profile.DOMNodesCreated++
+	}
+
 	var dnode *js.Object
 	switch vnode.Tag {
 	case tagText:
 		dnode = js.Global.Get("document").Call("createTextNode", vnode.Data)
 	case tagRaw:
-		dnode = js.Global.Get("document").Call("createRange").Call("createContextualFragment", vnode.Data)
+		// raw html can expand to any number of nodes, wrap it so that it is always exactly one DOM node
+		// like every other vnode, otherwise the indexes in Location would be off for everything after it
+		wrapper := rawWrapper()
+		dnode = js.Global.Get("document").Call("createElement", wrapper.Tag)
+		for k, v := range wrapper.Attributes {
+			dnode.Call("setAttribute", k, v)
+		}
+		dnode.Call("appendChild", js.Global.Get("document").Call("createRange").Call("createContextualFragment", vnode.Data))
 	default:
 		dnode = js.Global.Get("document").Call("createElement", vnode.Tag)
 
@@ -76,9 +218,16 @@ func RenderNode(vnode *VNode) *js.Object {
 		}
 	}
 
+	vnode.dom = dnode
 	return dnode
 }
 
+// rawWrapper is the element RenderNode puts raw html in, display:contents lays its children out as if they were the
+// wrapper's siblings
+func rawWrapper() *VNode {
+	return &VNode{Tag: rawWrapperTag, Attributes: map[string]string{"style": "display:contents"}}
+}
+
 func Begin(tag string) {
 	vnode := NewVNode(tag)
 	Active.Children = append(Active.Children, vnode)
@@ -86,27 +235,47 @@ func Begin(tag string) {
 	Active = vnode
 }
 
-func sortStrings(a []string, left int, right int) {
-	if left >= right {
+// sortStrings is a stable sort that doesn't allocate for the handful of keys an element usually has
+func sortStrings(a []string) {
+	if len(a) <= 12 {
+		for i := 1; i < len(a); i++ {
+			for j := i; j > 0 && a[j] < a[j-1]; j-- {
+				a[j], a[j-1] = a[j-1], a[j]
+			}
+		}
 		return
 	}
 
-	// partition
-	pivotIndex := (left + right) / 2
-	pivot := a[pivotIndex]
-	a[pivotIndex], a[right] = a[right], a[pivotIndex]
-	storeIndex := left
+	// bottom up merge sort for larger inputs, such as the keys of the debug overlay
+	src := a
+	dst := make([]string, len(a))
+	for width := 1; width < len(a); width *= 2 {
+		for left := 0; left < len(a); left += 2 * width {
+			mid := left + width
+			if mid > len(a) {
+				mid = len(a)
+			}
+			right := mid + width
+			if right > len(a) {
+				right = len(a)
+			}
 
-	for i := left; i < right; i++ {
-		if a[i] < pivot {
-			a[storeIndex], a[i] = a[i], a[storeIndex]
-			storeIndex++
+			i, j := left, mid
+			for k := left; k < right; k++ {
+				if i < mid && (j >= right || src[i] <= src[j]) {
+					dst[k] = src[i]
+					i++
+				} else {
+					dst[k] = src[j]
+					j++
+				}
+			}
 		}
+		src, dst = dst, src
+	}
+	if &src[0] != &a[0] {
+		copy(a, src)
 	}
-	a[storeIndex], a[right] = a[right], a[storeIndex]
-
-	sortStrings(a, left, storeIndex-1)
-	sortStrings(a, storeIndex+1, right)
 }
 
 func End(tag string) {
@@ -117,30 +286,140 @@ func End(tag string) {
 
 	// browser implementations of node.style differ, so convert it into an attribute on the node
 	if len(vnode.Styles) > 0 {
-		keys := []string{}
-		for k := range vnode.Styles {
-			keys = append(keys, k)
-		}
-		sortStrings(keys, 0, len(keys)-1)
-		style := ""
-		for _, k := range keys {
-			style += k + ":" + vnode.Styles[k] + ";"
-		}
-		vnode.Attributes["style"] = style
+		vnode.Attributes["style"] = serializeStyles(vnode.Styles)
 	}
 
+	hashVNode(vnode)
+
 	Active = Active.Parent
 }
 
+// serializeStyles builds the style attribute in scratch space that is reused across elements and frames, and returns
+// the same string for a style that was used in this frame or the last one. Natively looking up string(buf) doesn't
+// allocate, so only new styles do. Under gopherjs string(buf) makes a new javascript string every time, so every styled
+// element still leaves one behind as garbage, see BenchmarkStyles.
+func serializeStyles(styles map[string]string) string {
+	keys := styleKeys[:0]
+	for k := range styles {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+
+	buf := styleBuf[:0]
+	for _, k := range keys {
+		buf = append(buf, k...)
+		buf = append(buf, ':')
+		buf = append(buf, styles[k]...)
+		buf = append(buf, ';')
+	}
+	styleKeys = keys
+	styleBuf = buf
+
+	if style, ok := styleCache[string(buf)]; ok {
+		return style
+	}
+
+	style, ok := previousStyleCache[string(buf)]
+	if !ok {
+		style = string(buf)
+	}
+	styleCache[style] = style
+	return style
+}
+
+// rotateStyleCache drops styles that haven't been used for a whole frame
+func rotateStyleCache() {
+	for k := range previousStyleCache {
+		delete(previousStyleCache, k)
+	}
+	styleCache, previousStyleCache = previousStyleCache, styleCache
+}
+
+func hashVNode(vnode *VNode) {
+	if !hashing {
+		return
+	}
+
+	h := nodeHash{2166136261, 0x9747b28c}
+	h.writeString(vnode.Tag)
+	h.writeString(vnode.Data)
+
+	keys := hashKeys[:0]
+	for k := range vnode.Attributes {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+	for _, k := range keys {
+		h.writeString(k)
+		h.writeString(vnode.Attributes[k])
+	}
+	hashKeys = keys
+
+	for _, child := range vnode.Children {
+		for _, lane := range child.hash {
+			h.writeByte(byte(lane))
+			h.writeByte(byte(lane >> 8))
+			h.writeByte(byte(lane >> 16))
+			h.writeByte(byte(lane >> 24))
+		}
+	}
+
+	if h == (nodeHash{}) {
+		h[0] = 1
+	}
+	vnode.hash = h
+}
+
+// rehashVNode updates the hashes of vnode and its ancestors after vnode has been changed
+func rehashVNode(vnode *VNode) {
+	for ; vnode != nil; vnode = vnode.Parent {
+		hashVNode(vnode)
+	}
+}
+
+func (h *nodeHash) writeByte(c byte) {
+	// fnv-1a and a murmur style mix
+	h[0] = (h[0] ^ uint32(c)) * 16777619
+	h[1] = (h[1] ^ uint32(c)) * 0x5bd1e995
+	h[1] ^= h[1] >> 15
+}
+
+func (h *nodeHash) writeString(s string) {
+	for i := 0; i < len(s); i++ {
+		h.writeByte(s[i])
+	}
+	// terminate each field so that ("ab", "c") and ("a", "bc") hash differently
+	h.writeByte(0xff)
+}
+
 func Text(data string) {
 	vnode := NewVNode(tagText)
 	vnode.Data = data
+	hashVNode(vnode)
+	vnode.Parent = Active
 	Active.Children = append(Active.Children, vnode)
 }
 
-func Raw(data string) {
+// UnsafeRaw inserts data as html without any escaping, only pass it html you wrote yourself, see SafeRaw.
+//
+// However many nodes the html has, even none, it goes in the page inside a single <raw-html> element with
+// display:contents, so that it counts as one child when patches find nodes by their index. Selectors see the wrapper,
+// "ul > li" doesn't match an li from raw html, and it can't go directly in an element that only allows certain
+// children, like a table or a list, or anywhere inside svg, where UnsafeRaw panics.
+func UnsafeRaw(data string) {
+	if rawRestrictedParents[Active.Tag] {
+		panic("UnsafeRaw can't be used directly inside " + Active.Tag + ", wrap it in an element that can hold a " + rawWrapperTag)
+	}
+	for v := Active; v != nil; v = v.Parent {
+		if v.Tag == "svg" || v.Tag == "math" {
+			panic("UnsafeRaw can't be used inside " + v.Tag + ", its " + rawWrapperTag + " wrapper isn't rendered there")
+		}
+	}
+
 	vnode := NewVNode(tagRaw)
 	vnode.Data = data
+	hashVNode(vnode)
+	vnode.Parent = Active
 	Active.Children = append(Active.Children, vnode)
 }
 
@@ -158,34 +437,64 @@ func Style(args ...string) {
 
 func Tag(tag string, arg interface{}) {
 	Begin(tag)
+	drawContent(arg, "Tag")
+	End(tag)
+}
+
+// drawContent draws the contents of the active element, arg is either a string of text or a function that draws them
+func drawContent(arg interface{}, caller string) {
 	switch v := arg.(type) {
 	case func():
 		v()
 	case string:
 		Text(v)
 	default:
-		panic("invalid parameter to Tag")
+		panic("invalid parameter to " + caller)
 	}
-	End(tag)
 }
 
 func DiffNodes(o, n *VNode) []Patch {
-	return diffHelper(o, n, []int{})
+	checkLive(o)
+	checkLive(n)
+
+	if profile == nil {
+		return diffHelper(o, n, []int{})
+	}
+
+	start := now()
+	patches := diffHelper(o, n, []int{})
+	profile.DiffMs += now() - start
+	for _, patch := range patches {
+		profile.Patches[patch.Type]++
+	}
+	return patches
 }
 
 func diffHelper(o, n *VNode, loc []int) []Patch {
 	if o == nil || o.Tag != n.Tag || o.Attributes["id"] != n.Attributes["id"] {
-		return []Patch{{Type: patchReplace, VNode: n, Location: loc}}
+		return []Patch{{Type: patchReplace, VNode: n, Location: loc, node: o}}
+	}
+
+	// identical subtrees, which is most of them in a typical frame. Only the root of the subtree gets its DOM node
+	// now, the nodes under it find theirs when something needs them, see findDOM, so skipping doesn't depend on the
+	// subtree's size. Equal hashes of nodes that differ themselves are caught here, the chance of two different
+	// children colliding is left at the hash's 64 bits.
+	if hashing && o.hash == n.hash && o.hash != (nodeHash{}) && sameNode(o, n) {
+		n.dom = o.dom
+		return nil
 	}
 
 	if (o.Tag == tagText && n.Tag == tagText) || (o.Tag == tagRaw && n.Tag == tagRaw) {
 		// these nodes cannot have children
 		if o.Data == n.Data {
+			n.dom = o.dom
 			return nil
 		}
-		return []Patch{{Type: patchReplace, VNode: n, Location: loc}}
+		return []Patch{{Type: patchReplace, VNode: n, Location: loc, node: o}}
 	}
 
+	n.dom = o.dom
+
 	updated := false
 	attributes := map[string]string{}
 
@@ -209,7 +518,7 @@ func diffHelper(o, n *VNode, loc []int) []Patch {
 	patches := []Patch{}
 
 	if updated {
-		patches = append(patches, Patch{Type: patchUpdate, Attributes: attributes, Location: loc})
+		patches = append(patches, Patch{Type: patchUpdate, Attributes: attributes, Location: loc, node: n})
 	}
 
 	i := 0
@@ -222,30 +531,99 @@ func diffHelper(o, n *VNode, loc []int) []Patch {
 	}
 
 	for i < len(o.Children) {
-		patches = append(patches, Patch{Type: patchRemoveLastChild, Location: loc})
+		patches = append(patches, Patch{Type: patchRemoveLastChild, Location: loc, node: o.Children[i]})
 		i++
 	}
 
 	for i < len(n.Children) {
-		patches = append(patches, Patch{Type: patchAppendChild, VNode: n.Children[i], Location: loc})
+		patches = append(patches, Patch{Type: patchAppendChild, VNode: n.Children[i], Location: loc, node: n})
 		i++
 	}
 
 	return patches
 }
 
+// sameNode reports whether two nodes have the same tag, data, attributes and number of children, without looking at
+// the children themselves
+func sameNode(o, n *VNode) bool {
+	if o.Tag != n.Tag || o.Data != n.Data || len(o.Attributes) != len(n.Attributes) || len(o.Children) != len(n.Children) {
+		return false
+	}
+	for k, v := range n.Attributes {
+		if ov, ok := o.Attributes[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// findDOM returns the DOM node of vnode, whose location is loc, starting from the closest ancestor that knows its DOM
+// node or from root, the DOM node of the tree's root, and remembers it for next time
+func findDOM(vnode *VNode, loc []int, root *js.Object) *js.Object {
+	dnode, depth := root, 0
+	for v, i := vnode, len(loc); v != nil && i >= 0; v, i = v.Parent, i-1 {
+		if v.dom != nil {
+			dnode, depth = v.dom, i
+			break
+		}
+	}
+	for _, i := range loc[depth:] {
+		dnode = dnode.Get("childNodes").Index(i)
+	}
+	vnode.dom = dnode
+	return dnode
+}
+
+// domOf is findDOM for a vnode whose location isn't known, the indexes are looked up in its ancestors' children
+func domOf(vnode *VNode) *js.Object {
+	if vnode.dom != nil || vnode.Parent == nil {
+		return vnode.dom
+	}
+	parent := domOf(vnode.Parent)
+	if parent == nil {
+		return nil
+	}
+	for i, child := range vnode.Parent.Children {
+		if child == vnode {
+			vnode.dom = parent.Get("childNodes").Index(i)
+			break
+		}
+	}
+	return vnode.dom
+}
+
 func PatchDOM(patches []Patch, root *js.Object) {
+	if profile != nil {
+		start := now()
+		defer func() {
+			profile.PatchMs += now() - start
+		}()
+	}
+
 	for _, patch := range patches {
-		dnode := root
-		for _, i := range patch.Location {
-			dnode = dnode.Get("childNodes").Index(i)
+		var dnode *js.Object
+		switch {
+		case patch.node == nil:
+			// patches that were decoded have to find their node by location
+			dnode = root
+			for _, i := range patch.Location {
+				dnode = dnode.Get("childNodes").Index(i)
+			}
+			if patch.Type == patchRemoveLastChild {
+				dnode = dnode.Get("lastChild")
+			}
+		case patch.Type == patchRemoveLastChild && patch.node.dom == nil:
+			// the location is the parent's
+			dnode = findDOM(patch.node.Parent, patch.Location, root).Get("lastChild")
+		default:
+			dnode = findDOM(patch.node, patch.Location, root)
 		}
 
 		switch patch.Type {
 		case patchReplace:
 			dnode.Get("parentNode").Call("replaceChild", RenderNode(patch.VNode), dnode)
 		case patchRemoveLastChild:
-			dnode.Call("removeChild", dnode.Get("childNodes").Index(dnode.Get("childNodes").Get("length").Int()-1))
+			dnode.Get("parentNode").Call("removeChild", dnode)
 		case patchAppendChild:
 			dnode.Call("appendChild", RenderNode(patch.VNode))
 		case patchUpdate:
@@ -260,6 +638,50 @@ func PatchDOM(patches []Patch, root *js.Object) {
 	}
 }
 
+// PatchVNode applies patches to a vnode tree the same way PatchDOM applies them to the DOM and returns the new root
+func PatchVNode(root *VNode, patches []Patch) *VNode {
+	checkLive(root)
+
+	for _, patch := range patches {
+		var parent *VNode
+		vnode := root
+		index := 0
+		for _, i := range patch.Location {
+			parent = vnode
+			index = i
+			vnode = vnode.Children[i]
+		}
+
+		switch patch.Type {
+		case patchReplace:
+			if parent == nil {
+				root = patch.VNode
+			} else {
+				parent.Children[index] = patch.VNode
+			}
+			patch.VNode.Parent = parent
+			rehashVNode(parent)
+		case patchRemoveLastChild:
+			vnode.Children = vnode.Children[:len(vnode.Children)-1]
+			rehashVNode(vnode)
+		case patchAppendChild:
+			vnode.Children = append(vnode.Children, patch.VNode)
+			patch.VNode.Parent = vnode
+			rehashVNode(vnode)
+		case patchUpdate:
+			for k, v := range patch.Attributes {
+				if v == "" {
+					delete(vnode.Attributes, k)
+				} else {
+					vnode.Attributes[k] = v
+				}
+			}
+			rehashVNode(vnode)
+		}
+	}
+	return root
+}
+
 func Div(arg interface{}) {
 	Tag("div", arg)
 }
@@ -0,0 +1,303 @@
+package main
+
+import "testing"
+
+func TestDiffHashCollision(t *testing.T) {
+	o := tree(func() {
+		item("a", "one", false)
+	})
+	n := tree(func() {
+		item("a", "one", true)
+	})
+
+	// as if the two items had collided, every node's hash the same as its counterpart's
+	var collide func(o, n *VNode)
+	collide = func(o, n *VNode) {
+		n.hash = o.hash
+		for i := range n.Children {
+			collide(o.Children[i], n.Children[i])
+		}
+	}
+	collide(o.Children[0], n.Children[0])
+
+	patches := DiffNodes(o, n)
+	if got, want := EncodePatches(patches), `{"v":1,"p":[["u",[0],{"class":"completed","style":"color:#d9d9d9;text-decoration:line-through;"}]]}`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+// every patch from a diff knows the vnode whose DOM node it acts on, so PatchDOM doesn't have to walk its location
+func TestPatchNodes(t *testing.T) {
+	o := tree(func() {
+		item("a", "one", false)
+		item("b", "two", false)
+		item("c", "three", false)
+	})
+	n := tree(func() {
+		item("a", "one", true)
+		item("b", "2", false)
+	})
+	patches := DiffNodes(o, n)
+	n2 := tree(func() {
+		item("a", "one", true)
+		item("b", "2", false)
+		item("d", "four", false)
+	})
+	patches = append(patches, DiffNodes(n, n2)...)
+
+	want := []struct {
+		typ  string
+		node *VNode
+	}{
+		{patchUpdate, n.Children[0]},
+		{patchReplace, o.Children[1].Children[0].Children[0]},
+		{patchRemoveLastChild, o.Children[2]},
+		{patchAppendChild, n2},
+	}
+	if len(patches) != len(want) {
+		t.Fatalf("got %s", EncodePatches(patches))
+	}
+	for i, w := range want {
+		if patches[i].Type != w.typ || patches[i].node != w.node {
+			t.Errorf("patch %d is a %s on %p, want a %s on %p", i, patches[i].Type, patches[i].node, w.typ, w.node)
+		}
+	}
+}
+
+func rows(count int, changed int) func() {
+	return func() {
+		Begin("ul")
+		for i := 0; i < count; i++ {
+			text := "todo " + itoa(i)
+			if i == changed {
+				text += " changed"
+			}
+			item("row-"+itoa(i), text, i%3 == 0)
+		}
+		End("ul")
+	}
+}
+
+// BenchmarkDiff builds a frame and diffs it against the one before it, when nothing changed and when one row did, with
+// subtrees hashed and equal ones skipped and without hashing
+func BenchmarkDiff(b *testing.B) {
+	for _, count := range []int{1000, 10000} {
+		for _, changed := range []bool{false, true} {
+			for _, hashed := range []bool{true, false} {
+				name := itoa(count)
+				if changed {
+					name += "/one-change"
+				} else {
+					name += "/unchanged"
+				}
+				if hashed {
+					name += "/hashed"
+				} else {
+					name += "/plain"
+				}
+
+				b.Run(name, func(b *testing.B) {
+					saved := hashing
+					hashing = hashed
+					defer func() { hashing = saved }()
+
+					previous := tree(rows(count, -1))
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						// frames alternate between two versions of the row when it changes
+						changedRow := -1
+						if changed && i%2 == 0 {
+							changedRow = count / 2
+						}
+						root := tree(rows(count, changedRow))
+						DiffNodes(previous, root)
+						releaseVNode(previous)
+						previous = root
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRetainSubtree(t *testing.T) {
+	saved := vnodePool
+	vnodePool = nil
+	defer func() { vnodePool = saved }()
+
+	root := tree(func() {
+		item("a", "one", false)
+		item("b", "two", false)
+	})
+	kept := root.Children[1]
+	want := EncodeVNode(kept)
+	Retain(kept)
+	releaseVNode(root)
+
+	if kept.released || kept.Parent != nil {
+		t.Fatal("the retained subtree was recycled along with its tree")
+	}
+	for _, vnode := range vnodePool {
+		if vnode == kept || vnode == kept.Children[0] {
+			t.Fatal("part of the retained subtree is in the pool")
+		}
+	}
+
+	if got := EncodeVNode(kept); got != want {
+		t.Fatalf("the retained subtree changed to %s", got)
+	}
+
+	// once it is released as well its vnodes come out of the pool like any other, without being retained
+	releaseVNode(kept)
+	for len(vnodePool) > 0 {
+		if NewVNode("div").retained {
+			t.Fatal("a vnode from the pool is retained")
+		}
+	}
+}
+
+func TestVNodeHandle(t *testing.T) {
+	saved := vnodePool
+	vnodePool = nil
+	defer func() { vnodePool = saved }()
+
+	root := tree(func() {
+		item("a", "one", false)
+	})
+	h := HandleOf(root.Children[0])
+	if h.Get() != root.Children[0] {
+		t.Fatal("the handle doesn't return its vnode")
+	}
+	releaseVNode(root)
+
+	// the pool hands every vnode of the tree out again, so the handle's vnode looks live
+	for len(vnodePool) > 0 {
+		NewVNode("div")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("a handle to a recycled vnode that was reused returned it")
+		}
+	}()
+	h.Get()
+}
+
+// raw html is one vnode and one child in the page however many nodes it has, so the locations of its siblings don't
+// depend on it
+func TestRawNodeCount(t *testing.T) {
+	for _, html := range []string{"<b>1</b><i>2</i>three", "", "<p>one</p>"} {
+		o := tree(func() {
+			UnsafeRaw(html)
+			item("a", "one", false)
+		})
+		n := tree(func() {
+			UnsafeRaw(html)
+			item("a", "two", false)
+		})
+		if len(n.Children) != 2 || n.Children[0].Tag != tagRaw {
+			t.Fatalf("%q: expected a raw vnode and an item, got %s", html, EncodeVNode(n))
+		}
+		if got, want := EncodePatches(DiffNodes(o, n)), `{"v":1,"p":[["r",[1,0,0],"two"]]}`; got != want {
+			t.Errorf("%q: got %s, want %s", html, got, want)
+		}
+	}
+}
+
+func TestRawRestrictedParents(t *testing.T) {
+	for _, parents := range [][]string{
+		{"table"}, {"table", "tbody"}, {"table", "tbody", "tr"}, {"ul"}, {"ol"}, {"dl"}, {"select"}, {"select", "optgroup"},
+		{"svg"}, {"svg", "g"}, {"div", "svg", "foreignObject", "div"}, {"math"}, {"math", "mrow"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("raw html was allowed in %v", parents)
+				}
+				Root, Active = nil, nil
+			}()
+			Init("body")
+			for _, tag := range parents {
+				Begin(tag)
+			}
+			UnsafeRaw("<b>1</b>")
+		}()
+	}
+
+	// inside an element that can hold it is fine
+	tree(func() {
+		Begin("ul")
+		Begin("li")
+		UnsafeRaw("<b>1</b>")
+		End("li")
+		End("ul")
+		Begin("table")
+		Begin("tbody")
+		Begin("tr")
+		Begin("td")
+		UnsafeRaw("<b>1</b>")
+		End("td")
+		End("tr")
+		End("tbody")
+		End("table")
+	})
+}
+
+func TestRawWrapper(t *testing.T) {
+	w := rawWrapper()
+	// a custom element, which the parser leaves alone wherever phrasing content is allowed
+	if w.Tag != "raw-html" || len(w.Attributes) != 1 || w.Attributes["style"] != "display:contents" {
+		t.Fatalf("the wrapper is %s", EncodeVNode(w))
+	}
+
+	// the wrapper is added when the raw html is rendered, patches carry the html as it is
+	root := tree(func() {
+		Div(func() {
+			UnsafeRaw("<b>1</b>two")
+		})
+	})
+	if got, want := EncodeVNode(root), `["body",{},[["div",{},[{"raw":"<b>1</b>two"}]]]]`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+// concatStyles is how styles were serialized before serializeStyles, for BenchmarkStyles to compare against
+func concatStyles(styles map[string]string) string {
+	keys := []string{}
+	for k := range styles {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+	style := ""
+	for _, k := range keys {
+		style += k + ":" + styles[k] + ";"
+	}
+	return style
+}
+
+// BenchmarkStyles serializes the styles of a frame of 1,000 elements, a few different styles between them like the
+// completed and highlighted todos of a list
+func BenchmarkStyles(b *testing.B) {
+	styles := []map[string]string{
+		{"text-decoration": "line-through", "color": "#d9d9d9"},
+		{"background": "#fffbe6"},
+		{"display": "none"},
+	}
+	for _, c := range []struct {
+		name      string
+		serialize func(map[string]string) string
+	}{
+		{"concat", concatStyles},
+		{"cached", serializeStyles},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < 1000; j++ {
+					c.serialize(styles[j%len(styles)])
+				}
+				rotateStyleCache()
+			}
+		})
+	}
+}
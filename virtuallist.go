@@ -0,0 +1,92 @@
+package main
+
+type VirtualListOptions struct {
+	// height of the scrolling area in pixels, the list is shorter if its rows don't fill it
+	Height float64
+	// used until rows have been measured
+	EstimatedRowHeight float64
+	// rows drawn beyond each edge of the visible area so that fast scrolling doesn't show gaps
+	Overscan int
+	// rows that are drawn even when scrolled out of view, such as one being edited, so that they keep focus and input values
+	Keep func(i int) bool
+}
+
+// average measured row height of each list, by id
+var virtualRowHeights = map[string]float64{}
+
+// VirtualList draws a scrolling element containing only the rows that are in view, with spacers standing in for the rest.
+// row is called inside each row's element like the function passed to Div. Rows that set an id are measured, and their
+// average height is used to place the rows that aren't drawn.
+func VirtualList(id string, count int, options VirtualListOptions, row func(i int)) {
+	if options.EstimatedRowHeight <= 0 {
+		panic("VirtualList needs an EstimatedRowHeight")
+	}
+
+	Div(func() {
+		Id(id)
+
+		Style(
+			"overflow-y", "auto",
+			"max-height", px(options.Height),
+			// the spacers change size while scrolling, which would otherwise make the browser adjust the scroll position
+			"overflow-anchor", "none",
+		)
+
+		rowHeight := virtualRowHeights[id]
+		if rowHeight == 0 {
+			rowHeight = options.EstimatedRowHeight
+		}
+
+		viewport := options.Height
+		if r, ok := Measure(id); ok && r.Height > 0 {
+			viewport = r.Height
+		}
+
+		scroll := ScrollTop(id)
+		first := int(scroll/rowHeight) - options.Overscan
+		last := int((scroll+viewport)/rowHeight) + options.Overscan
+
+		measuredHeight := 0.0
+		measuredRows := 0
+		skipped := 0
+		for i := 0; i < count; i++ {
+			if (i < first || i > last) && (options.Keep == nil || !options.Keep(i)) {
+				skipped++
+				continue
+			}
+
+			drawSpacer(float64(skipped) * rowHeight)
+			skipped = 0
+
+			Div(func() {
+				row(i)
+
+				if rowId := Active.Attributes["id"]; rowId != "" {
+					if r, ok := Measure(rowId); ok {
+						measuredHeight += r.Height
+						measuredRows++
+					}
+				}
+			})
+		}
+		drawSpacer(float64(skipped) * rowHeight)
+
+		if measuredRows > 0 {
+			virtualRowHeights[id] = measuredHeight / float64(measuredRows)
+		}
+	})
+}
+
+func drawSpacer(height float64) {
+	if height <= 0 {
+		return
+	}
+
+	Div(func() {
+		Style("height", px(height))
+	})
+}
+
+func px(f float64) string {
+	return itoa(int(f+0.5)) + "px"
+}
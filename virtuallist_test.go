@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVirtualList(t *testing.T) {
+	todos := []Todo{}
+	for i := 0; i < 100; i++ {
+		todos = append(todos, Todo{Id: i, Text: "todo " + itoa(i), Completed: i%2 == 1})
+	}
+	a := newTestApp(t, todos)
+
+	drawn := func() []string {
+		ids := []string{}
+		list := findVNode(PreviousRoot, "todo-list")
+		for _, child := range list.Children {
+			if id := child.Attributes["id"]; id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	spacers := func() []string {
+		heights := []string{}
+		for _, child := range findVNode(PreviousRoot, "todo-list").Children {
+			if child.Attributes["id"] == "" {
+				heights = append(heights, child.Styles["height"])
+			}
+		}
+		return heights
+	}
+
+	// 580px of rows 58px high with 5 more below, and a spacer for the other 84
+	a.frame("#/lists/0/all", nil)
+	if ids := drawn(); len(ids) != 16 || ids[0] != "todo-item-0" || ids[15] != "todo-item-15" {
+		t.Fatalf("drew %v", ids)
+	}
+	if s := spacers(); len(s) != 1 || s[0] != "4872px" {
+		t.Fatalf("spacers %v", s)
+	}
+
+	// scrolled down by 50 rows, 5 more are drawn on either side
+	a.input(InputEvent{Type: eventScroll, Id: "todo-list", Scroll: 50 * 58})
+	a.frame("#/lists/0/all", nil)
+	if ids := drawn(); len(ids) != 21 || ids[0] != "todo-item-45" || ids[20] != "todo-item-65" {
+		t.Fatalf("drew %v after scrolling", ids)
+	}
+	if s := spacers(); len(s) != 2 || s[0] != "2610px" || s[1] != "1972px" {
+		t.Fatalf("spacers %v after scrolling", s)
+	}
+
+	// the list is of the todos the filter shows, there are only 50 active ones to scroll through
+	a.frame("#/lists/0/active", nil)
+	if ids := drawn(); len(ids) != 5 || ids[0] != "todo-item-90" || ids[4] != "todo-item-98" {
+		t.Fatalf("drew %v of the active todos", ids)
+	}
+	a.input(InputEvent{Type: eventScroll, Id: "todo-list", Scroll: 0})
+	a.frame("#/lists/0/active", nil)
+	if ids := drawn(); len(ids) != 16 || ids[1] != "todo-item-2" {
+		t.Fatalf("drew %v of the active todos scrolled back up", ids)
+	}
+
+	// a todo being edited is drawn wherever it is, so that it keeps focus
+	startEditing(80)
+	a.frame("#/lists/0/active", nil)
+	ids := drawn()
+	if len(ids) != 17 || ids[16] != "todo-item-80" || !strings.Contains(EncodeVNode(PreviousRoot), `"edit-todo-item-80"`) {
+		t.Fatalf("drew %v while editing a todo out of view", ids)
+	}
+}
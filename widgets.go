@@ -0,0 +1,51 @@
+package main
+
+const (
+	keyTab   = 9
+	keyEnter = 13
+	keyEsc   = 27
+	keySpace = 32
+)
+
+// Button draws a div that behaves like a button: it can be focused, is announced as a button and is activated by
+// clicks, enter and space. label is the accessible name, it can be empty if arg draws text. Reports whether it was activated.
+func Button(id string, label string, arg interface{}) bool {
+	return drawWidget(id, "button", label, arg)
+}
+
+// Checkbox is a Button announced as a checkbox, it reports whether it was toggled
+func Checkbox(id string, label string, checked bool, arg interface{}) bool {
+	return drawWidget(id, "checkbox", label, arg, "aria-checked", ariaBool(checked))
+}
+
+// ToggleButton is a Button that stays pressed, it reports whether it was toggled
+func ToggleButton(id string, label string, pressed bool, arg interface{}) bool {
+	return drawWidget(id, "button", label, arg, "aria-pressed", ariaBool(pressed))
+}
+
+func drawWidget(id string, role string, label string, arg interface{}, attributes ...string) bool {
+	Begin("div")
+	Id(id)
+	Attr("role", role, "tabindex", "0")
+	if label != "" {
+		Attr("aria-label", label)
+	}
+	Attr(attributes...)
+
+	// space is also kept from scrolling the page, see the keydown handler in Setup
+	activated := Clicked(id)
+	if Keyup(id, keyEnter) || Keyup(id, keySpace) {
+		activated = true
+	}
+
+	drawContent(arg, "widget")
+	End("div")
+	return activated
+}
+
+func ariaBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestButton(t *testing.T) {
+	newTestApp(t, nil)
+
+	for _, c := range []struct {
+		ev        InputEvent
+		activated bool
+	}{
+		{InputEvent{Type: eventClick, Id: "b"}, true},
+		{InputEvent{Type: eventKeyup, Id: "b", Code: keyEnter}, true},
+		{InputEvent{Type: eventKeyup, Id: "b", Code: keySpace}, true},
+		{InputEvent{Type: eventKeyup, Id: "b", Code: keyEsc}, false},
+		{InputEvent{Type: eventKeyup, Id: "other", Code: keyEnter}, false},
+		{InputEvent{Type: eventClick, Id: "other"}, false},
+	} {
+		clickId, keyupId, keyupCode = "", "", 0
+		applyInput(c.ev)
+		activated := false
+		root := tree(func() {
+			activated = Button("b", "Close", "x")
+		})
+		if activated != c.activated {
+			t.Errorf("%+v activated the button %v", c.ev, activated)
+		}
+
+		b := root.Children[0]
+		if b.Attributes["role"] != "button" || b.Attributes["tabindex"] != "0" || b.Attributes["aria-label"] != "Close" {
+			t.Fatalf("the button is %s", EncodeVNode(b))
+		}
+		if !clickable["b"] || !keyupable["b"] {
+			t.Fatal("the button doesn't listen for clicks and keys")
+		}
+	}
+}
+
+func TestCheckbox(t *testing.T) {
+	newTestApp(t, nil)
+
+	for _, checked := range []bool{false, true} {
+		root := tree(func() {
+			Checkbox("c", "", checked, "done")
+		})
+		c := root.Children[0]
+		if c.Attributes["role"] != "checkbox" || c.Attributes["aria-checked"] != ariaBool(checked) {
+			t.Fatalf("the checkbox is %s", EncodeVNode(c))
+		}
+		// its text is its name
+		if _, ok := c.Attributes["aria-label"]; ok {
+			t.Fatalf("the checkbox has a label as well as its text %s", EncodeVNode(c))
+		}
+	}
+}
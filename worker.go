@@ -0,0 +1,286 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+// Messages between a host, which owns the DOM, and the side that runs render(), either a web worker or a server:
+//
+//	host -> render: {"type":"input","event":event}     user input, see InputEvent
+//	host -> render: {"type":"frame","event":event}     a frame event with the input values, selection, location and rects
+//	host -> render: {"type":"storage","items":{}}      what a web worker's host has saved, sent before anything else
+//	render -> host: {"type":"request-frame"}
+//	render -> host: {"type":"save","key":"k","value":"v"} or {"type":"save","key":"k","remove":true}
+//	render -> host: {"type":"location","location":"#/active"}
+//	render -> host: {"type":"output","patches":patches,"values":{},"focus":"id","selection":[0,0],
+//	                 "click":[ids],"dblclick":[ids],"hover":[ids],"keyup":{"id":[codes]},
+//	                 "scroll":[ids],"link":[ids],"shortcut":[keys],"file":[ids],"reveal":[ids],
+//	                 "download":[{"name":"todos.csv","type":"text/csv","data":"..."}],
+//	                 "notify":[{"title":"...","body":"..."}],"asknotify":false,"measure":[ids],"trap":"id"}
+//
+// the host only sends a new frame once it has applied the output of the previous one, so that the
+// input values it snapshots are never older than the patches that produced them.
+
+const (
+	messageInput        = "input"
+	messageFrame        = "frame"
+	messageRequestFrame = "request-frame"
+	messageLocation     = "location"
+	messageOutput       = "output"
+	messageStorage      = "storage"
+	messageSave         = "save"
+)
+
+// InWorker reports whether this script is running inside a web worker
+func InWorker() bool {
+	return js.Global.Get("document") == js.Undefined && js.Global.Get("importScripts") != js.Undefined
+}
+
+// RunWorker renders frames in response to messages from a host started with StartWorker
+func RunWorker() {
+	b := newMessageBackend(func(msg string) {
+		js.Global.Call("postMessage", msg)
+	}, browserBackend{}.Now)
+	b.acceptStorage = true
+	backend = b
+
+	js.Global.Call("addEventListener", "message", func(e *js.Object) {
+		b.Receive(e.Get("data").String())
+	})
+
+	Rerender()
+}
+
+func eventMessage(messageType string, ev InputEvent) string {
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageType)
+	w.Raw(`,"event":`)
+	writeInputEvent(w, ev)
+	w.Raw("}")
+	return w.Done()
+}
+
+// messageBackend renders for a host on the other side of send
+type messageBackend struct {
+	send      func(msg string)
+	now       func() float64
+	frame     InputEvent
+	patches   []Patch
+	measure   []string
+	reveal    []string
+	downloads []File
+	notify    []Notification
+	askNotify bool
+	location  string
+	// what Restore was given, for Flush to send
+	values    map[string]string
+	focusId   string
+	selection [2]int
+
+	// a request for a frame has been sent and the frame hasn't come yet
+	frameRequested bool
+
+	// only a web worker takes the host's storage, a server's todos aren't up to its clients
+	acceptStorage bool
+}
+
+func newMessageBackend(send func(msg string), now func() float64) *messageBackend {
+	return &messageBackend{send: send, now: now}
+}
+
+// Receive handles a message from the host and reports whether a frame was rendered
+func (b *messageBackend) Receive(msg string) bool {
+	v, err := parseJSON(msg)
+	if err != nil {
+		print("invalid message", err.Error())
+		return false
+	}
+
+	m, _ := v.(map[string]interface{})
+	messageType := jsonString(m, "type")
+	if messageType == messageStorage {
+		if b.acceptStorage {
+			loadStorage(&hostStorage{items: jsonStringMap(m["items"]), send: b.send})
+		}
+		return false
+	}
+
+	ev, err := readInputEvent(m["event"])
+	if err != nil {
+		print("invalid message", err.Error())
+		return false
+	}
+
+	switch messageType {
+	case messageInput:
+		Input(ev)
+	case messageFrame:
+		b.frame = ev
+		b.location = ev.Location
+		b.frameRequested = false
+		Frame()
+		return true
+	}
+	return false
+}
+
+// hostStorage reads what the host had saved when it started and sends every change back for the host to save
+type hostStorage struct {
+	items map[string]string
+	send  func(msg string)
+}
+
+func (s *hostStorage) Get(key string) (string, bool) {
+	value, ok := s.items[key]
+	return value, ok
+}
+
+func (s *hostStorage) Set(key string, value string) {
+	s.items[key] = value
+
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageSave)
+	w.Raw(`,"key":`)
+	w.String(key)
+	w.Raw(`,"value":`)
+	w.String(value)
+	w.Raw("}")
+	s.send(w.Done())
+}
+
+func (s *hostStorage) Remove(key string) {
+	delete(s.items, key)
+
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageSave)
+	w.Raw(`,"key":`)
+	w.String(key)
+	w.Raw(`,"remove":true}`)
+	s.send(w.Done())
+}
+
+func (b *messageBackend) Snapshot(focusId string) (map[string]string, [2]int, bool) {
+	return b.frame.Values, b.frame.Selection, b.frame.HasSelection
+}
+
+func (b *messageBackend) Patch(patches []Patch) {
+	b.patches = append(b.patches, patches...)
+}
+
+func (b *messageBackend) Restore(values map[string]string, focusId string, selection [2]int) {
+	b.values = values
+	b.focusId = focusId
+	b.selection = selection
+}
+
+// Flush sends the frame's output
+func (b *messageBackend) Flush() {
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageOutput)
+	w.Raw(`,"patches":`)
+	writePatches(w, b.patches)
+	w.Raw(`,"values":`)
+	w.StringMap(b.values)
+	w.Raw(`,"focus":`)
+	w.String(b.focusId)
+	w.Raw(`,"selection":`)
+	w.Ints(b.selection[:])
+	w.Raw(`,"click":`)
+	w.Strings(sortedKeys(clickable))
+	w.Raw(`,"dblclick":`)
+	w.Strings(sortedKeys(doubleClickable))
+	w.Raw(`,"hover":`)
+	w.Strings(sortedKeys(hoverable))
+	w.Raw(`,"keyup":{`)
+	for i, id := range sortedKeys(keyupable) {
+		if i > 0 {
+			w.Raw(",")
+		}
+		w.String(id)
+		w.Raw(":")
+		w.Ints(keyupableCodes[id])
+	}
+	w.Raw(`},"scroll":`)
+	w.Strings(sortedKeys(scrollable))
+	w.Raw(`,"link":`)
+	w.Strings(sortedKeys(linkable))
+	w.Raw(`,"shortcut":`)
+	w.Strings(sortedKeys(shortcuts))
+	w.Raw(`,"file":`)
+	w.Strings(sortedKeys(uploadable))
+	w.Raw(`,"reveal":`)
+	w.Strings(b.reveal)
+	w.Raw(`,"download":`)
+	writeFiles(w, b.downloads)
+	w.Raw(`,"notify":`)
+	writeNotifications(w, b.notify)
+	w.Raw(`,"asknotify":`)
+	w.Bool(b.askNotify)
+	w.Raw(`,"measure":`)
+	w.Strings(b.measure)
+	w.Raw(`,"trap":`)
+	w.String(activeTrap)
+	w.Raw("}")
+
+	b.patches = nil
+	b.values = nil
+	b.measure = nil
+	b.reveal = nil
+	b.downloads = nil
+	b.notify = nil
+	b.askNotify = false
+	b.send(w.Done())
+}
+
+func (b *messageBackend) ScrollIntoView(ids []string) {
+	b.reveal = ids
+}
+
+func (b *messageBackend) Download(files []File) {
+	b.downloads = files
+}
+
+func (b *messageBackend) Notify(notes []Notification, ask bool) {
+	b.notify = notes
+	b.askNotify = ask
+}
+
+// the host measures after applying the output and sends the rects with the next frame
+func (b *messageBackend) Measure(ids []string) {
+	b.measure = ids
+}
+
+func (b *messageBackend) Rects() map[string]Rect {
+	return copyRects(b.frame.Rects)
+}
+
+func (b *messageBackend) Location() string {
+	return b.location
+}
+
+func (b *messageBackend) SetLocation(location string) {
+	b.location = location
+
+	w := &jsonWriter{}
+	w.Raw(`{"type":`)
+	w.String(messageLocation)
+	w.Raw(`,"location":`)
+	w.String(location)
+	w.Raw("}")
+	b.send(w.Done())
+}
+
+// RequestFrame asks the host for a frame unless it has already been asked
+func (b *messageBackend) RequestFrame() {
+	if b.frameRequested {
+		return
+	}
+	b.frameRequested = true
+	b.send(`{"type":"` + messageRequestFrame + `"}`)
+}
+
+func (b *messageBackend) Now() float64 {
+	return b.now()
+}
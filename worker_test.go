@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestFrameOnce(t *testing.T) {
+	a := newTestApp(t, []Todo{{Id: 0, Text: "a"}})
+	a.frame("", nil)
+
+	count := func(messageType string) int {
+		n := 0
+		for _, msg := range a.out {
+			if strings.HasPrefix(msg, `{"type":"`+messageType+`"`) {
+				n++
+			}
+		}
+		return n
+	}
+
+	a.out = nil
+	Rerender()
+	Rerender()
+	if n := count(messageRequestFrame); n != 1 {
+		t.Fatalf("asked for %d frames before the first came", n)
+	}
+
+	a.frame("", nil)
+	if n := count(messageOutput); n != 1 || !strings.HasPrefix(a.last(), `{"type":"output"`) {
+		t.Fatalf("sent %d outputs for one frame %v", n, a.out)
+	}
+	Rerender()
+	if n := count(messageRequestFrame); n != 2 {
+		t.Fatal("didn't ask for a frame after the last one came")
+	}
+}